@@ -0,0 +1,107 @@
+package main
+
+import (
+	"app-store-server/internal/app"
+	"app-store-server/internal/variant"
+	"app-store-server/pkg/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	lintOutputText = "text"
+	lintOutputJSON = "json"
+)
+
+func newLintCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "lint <dir>",
+		Short: "Validate an app chart directory",
+		Long: `Run the checks the server applies to an app on a local chart directory:
+manifest parsing and template rendering, validation rules, helm packaging and
+image extraction. No database, search engine or network is needed. The command
+exits non-zero when the app would be rejected.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != lintOutputText && output != lintOutputJSON {
+				return fmt.Errorf("unknown output format %q, use %s or %s", output, lintOutputText, lintOutputJSON)
+			}
+
+			err := variant.Init()
+			if err != nil {
+				return err
+			}
+
+			result, err := app.LintAppDir(args[0])
+			if err != nil {
+				return err
+			}
+
+			if output == lintOutputJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(result)
+			} else {
+				printLintResult(os.Stdout, result)
+			}
+			if err != nil {
+				return err
+			}
+
+			if result.Failed() {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	cmd.SilenceUsage = true
+	cmd.Flags().StringVarP(&output, "output", "o", lintOutputText, "output format, text or json")
+
+	return cmd
+}
+
+func printLintResult(w io.Writer, r *app.LintResult) {
+	fmt.Fprintf(w, "app:     %s %s\n", r.Name, r.Version)
+	if r.Chart != "" {
+		fmt.Fprintf(w, "chart:   %s\n", r.Chart)
+	}
+	fmt.Fprintf(w, "images:  %d\n", len(r.Images))
+	for _, image := range r.Images {
+		fmt.Fprintf(w, "  %s\n", image)
+	}
+
+	printIssues := func(title string, issues []models.ValidationIssue) {
+		if len(issues) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s:\n", title)
+		for _, issue := range issues {
+			if issue.Field != "" {
+				fmt.Fprintf(w, "  %s: %s (%s)\n", issue.Field, issue.Message, issue.Rule)
+			} else {
+				fmt.Fprintf(w, "  %s (%s)\n", issue.Message, issue.Rule)
+			}
+		}
+	}
+	printIssues("errors", r.Report.Errors)
+	printIssues("warnings", r.Report.Warnings)
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(w, "build errors:\n")
+		for _, e := range r.Errors {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+
+	if r.Failed() {
+		fmt.Fprintln(w, "FAIL")
+	} else {
+		fmt.Fprintln(w, "OK")
+	}
+}
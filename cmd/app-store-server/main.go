@@ -47,6 +47,7 @@ func newAPPServerCommand() *cobra.Command {
 	}
 
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+	cmd.AddCommand(newLintCommand())
 
 	return cmd
 }
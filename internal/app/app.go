@@ -6,7 +6,10 @@ import (
 	"app-store-server/internal/gitapp"
 	"app-store-server/internal/helm"
 	"app-store-server/internal/images"
+	"app-store-server/internal/leader"
 	"app-store-server/internal/mongo"
+	"app-store-server/internal/validator"
+	"app-store-server/internal/variant"
 	"app-store-server/pkg/models"
 	"app-store-server/pkg/utils"
 	"bytes"
@@ -17,7 +20,6 @@ import (
 	"os"
 	"os/exec"
 	"path"
-	"reflect"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -33,8 +35,7 @@ import (
 )
 
 const (
-	DisableCategoriesEnv = "DISABLE_CATEGORIES"
-	ImagesSourceEnv      = "IMAGES_SOURCE"
+	ImagesSourceEnv = "IMAGES_SOURCE"
 	// DefaultConcurrency is the default number of concurrent workers for processing apps
 	DefaultConcurrency = 10
 	// ConcurrencyEnv is the environment variable name for setting concurrency
@@ -46,30 +47,94 @@ var isGitUpdating int32
 var isAppInfoUpdating int32
 var isImageProcessing int32
 
-func getDisableCategories() string {
-	disableCategories := os.Getenv(DisableCategoriesEnv)
-	if disableCategories != "" {
-		return disableCategories
-	}
+// gitUpdatePending is set when a sync is requested while another one is running,
+// so bursts of webhook pushes collapse into a single follow-up run.
+var gitUpdatePending int32
 
-	return ""
-}
+// pendingTrigger holds the trigger of the latest pending request
+var pendingTrigger atomic.Value
+
+// syncRequestPollInterval is how often the leader looks for the syncs requested
+// on the other replicas
+const syncRequestPollInterval = 10 * time.Second
 
 func Init() error {
 	// 异步初始化，不阻塞HTTP服务启动
-	go func() {
-		err := UpdateAppInfosToDB()
-		if err != nil {
-			glog.Warningf("Async app initialization failed: %s", err.Error())
-		}
-	}()
+	leader.OnStartedLeading(startLeading)
+	if !leader.IsLeader() {
+		go func() {
+			err := followLeader(true)
+			if err != nil {
+				glog.Warningf("Async app initialization failed: %s", err.Error())
+			}
+		}()
+	}
 
 	// 启动定时更新循环
 	go pullAndUpdateLoop()
+	if leader.Enabled() {
+		go syncRequestLoop()
+	}
 
 	return nil
 }
 
+// hasLed is set once the replica led, a later leadership is a takeover
+var hasLed int32
+
+// startLeading re-ingests every app when the replica becomes the leader and
+// rebuilds the ES index behind the one the other replicas keep serving.
+func startLeading() {
+	trigger := models.SyncTriggerStartup
+	if !atomic.CompareAndSwapInt32(&hasLed, 0, 1) {
+		trigger = models.SyncTriggerTakeover
+	}
+
+	err := seedCategoryPolicies()
+	if err != nil {
+		glog.Warningf("seedCategoryPolicies failed: %s", err.Error())
+	}
+
+	run := startSyncRun(trigger)
+	run.rebuildIndex = true
+	run.update(func(r *models.SyncRun) {
+		r.Full = true
+		r.NewCommit, _ = gitapp.GetLastHash()
+	})
+
+	err = UpdateAppInfosToDB(run)
+	run.finish(err)
+	if err != nil {
+		glog.Warningf("Async app initialization failed: %s", err.Error())
+	}
+}
+
+// followLeader brings the checkouts of a replica that is not the leader to the
+// catalog the leader built and packages the charts it serves. Nothing shared
+// is written, force packages the charts even if no checkout moved.
+func followLeader(force bool) error {
+	err := gitapp.Follow()
+	if err != nil {
+		if !errors.Is(err, git.NoErrAlreadyUpToDate) || !force {
+			return err
+		}
+	}
+
+	if !atomic.CompareAndSwapInt32(&isAppInfoUpdating, 0, 1) {
+		glog.Infof("UpdateAppInfosToDB is already running, skipping this call")
+		return nil
+	}
+	defer atomic.StoreInt32(&isAppInfoUpdating, 0)
+
+	appDirs, err := gitapp.ListAppDirs()
+	if err != nil {
+		return err
+	}
+
+	_, _, _, err = processAppDirs(appDirs, false)
+	return err
+}
+
 func packApp(app *models.ApplicationInfoEntry) *models.ApplicationInfoFullData {
 
 	fullData := &models.ApplicationInfoFullData{
@@ -96,20 +161,82 @@ func packApps(apps []*models.ApplicationInfoEntry) []*models.ApplicationInfoFull
 	return fullDataList
 }
 
-func UpdateAppInfosToDB() error {
+// UpdateAppInfosToDB re-ingests every app directory of all catalog sources.
+func UpdateAppInfosToDB(run *syncRecorder) error {
 	// Use atomic operation to prevent concurrent execution
 	if !atomic.CompareAndSwapInt32(&isAppInfoUpdating, 0, 1) {
 		glog.Infof("UpdateAppInfosToDB is already running, skipping this call")
+		skipSyncRun(run)
 		return nil
 	}
 
 	// Ensure state is reset when function exits
 	defer atomic.StoreInt32(&isAppInfoUpdating, 0)
 
+	return updateAppInfosToDB(nil, run)
+}
+
+// UpdateChangedAppInfosToDB re-ingests only the app directories changed between
+// oldHash and newHash. The other apps are carried forward to newHash as they are.
+func UpdateChangedAppInfosToDB(oldHash, newHash string, changes *gitapp.AppDirChanges, run *syncRecorder) error {
+	if !atomic.CompareAndSwapInt32(&isAppInfoUpdating, 0, 1) {
+		glog.Infof("UpdateAppInfosToDB is already running, skipping this call")
+		skipSyncRun(run)
+		return nil
+	}
+	defer atomic.StoreInt32(&isAppInfoUpdating, 0)
+
+	glog.Infof("incremental update %s..%s, updated:%v, removed:%v", oldHash, newHash, changes.Updated, changes.Removed)
+
+	// the updated apps are re-ingested, one failing validation must not stay published
+	exclude := append([]string{}, changes.Removed...)
+	for _, d := range changes.Updated {
+		exclude = append(exclude, d.AppName())
+	}
+
+	err := run.leading()
+	if err != nil {
+		return err
+	}
+
+	err = mongo.CarryForwardAppInfos(oldHash, newHash, exclude)
+	if err != nil {
+		glog.Warningf("Failed to carry forward app infos: %s", err.Error())
+		return err
+	}
+
+	// a nil slice means all apps, keep an empty one when nothing changed
+	appDirs := changes.Updated
+	if appDirs == nil {
+		appDirs = []gitapp.AppDir{}
+	}
+
+	return updateAppInfosToDB(appDirs, run)
+}
+
+// updateAppInfosToDB processes appDirs, or every app directory when appDirs is nil.
+func updateAppInfosToDB(appDirs []gitapp.AppDir, run *syncRecorder) error {
+	var err error
+	if appDirs == nil {
+		appDirs, err = gitapp.ListAppDirs()
+		if err != nil {
+			return err
+		}
+	}
+
 	// First, quickly process app info without images to avoid blocking startup
-	infos, err := GetAppInfosFromGitDir(constants.AppGitLocalDir, false)
+	infos, failed, reports, err := processAppDirs(appDirs, false)
+	run.update(func(r *models.SyncRun) {
+		r.Succeeded = appNames(infos)
+		r.Failed = failed
+	})
+	// processing takes long, the leadership may have moved on meanwhile
+	if leadErr := run.leading(); leadErr != nil {
+		return leadErr
+	}
+	saveValidationReports(reports)
 	if err != nil {
-		glog.Warningf("GetAppInfosFromGitDir %s err:%s", constants.AppGitLocalDir, err.Error())
+		glog.Warningf("processAppDirs err:%s", err.Error())
 		return err
 	}
 
@@ -121,9 +248,39 @@ func UpdateAppInfosToDB() error {
 		return err
 	}
 
+	err = run.leading()
+	if err != nil {
+		return err
+	}
+
+	removed, err := reconcileRemovedApps(infos)
+	if err != nil {
+		glog.Warningf("Failed to remove deleted apps: %s", err.Error())
+	}
+	run.update(func(r *models.SyncRun) {
+		r.Removed = removed
+	})
+	catalogChanged()
+
+	checkDependencyGraph(run)
+
 	//sync info from mongodb to es
 	go func() {
-		err := es.SyncInfoFromMongo()
+		err := run.leading()
+		if err != nil {
+			glog.Warningf("skip es sync: %v", err)
+			return
+		}
+
+		run.startPhase(esPhase)
+		if run.rebuildIndex {
+			err = es.RebuildIndex(func() bool {
+				return run.leading() == nil
+			})
+		} else {
+			err = es.SyncInfoFromMongo()
+		}
+		run.endPhase(esPhase, nil, err)
 		if err != nil {
 			glog.Warningf("es.SyncInfoFromMongo failed: %v", err)
 			return
@@ -135,14 +292,19 @@ func UpdateAppInfosToDB() error {
 		go func() {
 			if !atomic.CompareAndSwapInt32(&isImageProcessing, 0, 1) {
 				glog.Infof("Image processing is already running, skipping this call")
+				run.update(func(r *models.SyncRun) {
+					r.ImagePhase.Error = "image processing of another run is in progress"
+				})
 				return
 			}
 			defer atomic.StoreInt32(&isImageProcessing, 0)
 
 			glog.Infof("Starting background image processing...")
-			_, err = GetAppInfosFromGitDir(constants.AppGitLocalDir, true)
+			run.startPhase(imagePhase)
+			_, failed, _, err := processAppDirs(appDirs, true)
+			run.endPhase(imagePhase, failed, err)
 			if err != nil {
-				glog.Warningf("GetAppInfosFromGitDir with packageImage=true failed: %v", err)
+				glog.Warningf("processAppDirs with packageImage=true failed: %v", err)
 			} else {
 				glog.Infof("Background image processing completed successfully")
 			}
@@ -155,23 +317,120 @@ func UpdateAppInfosToDB() error {
 func pullAndUpdateLoop() {
 	for {
 		time.Sleep(time.Duration(5) * time.Minute)
-		err := GitPullAndUpdate(false)
+		err := GitPullAndUpdate(false, models.SyncTriggerSchedule)
 		if err != nil {
 			glog.Warningf("%s", err.Error())
 		}
+
+		if leader.IsLeader() {
+			err = refreshAppLabels()
+			if err != nil {
+				glog.Warningf("refreshAppLabels err:%s", err.Error())
+			}
+		}
 	}
 }
 
-func GitPullAndUpdate(force bool) error {
+func GitPullAndUpdate(force bool, trigger string) error {
 	// 使用原子操作检查并设置状态，实现防重入
 	if !atomic.CompareAndSwapInt32(&isGitUpdating, 0, 1) {
 		return nil
 	}
 
-	// 确保函数结束时重置状态
-	defer atomic.StoreInt32(&isGitUpdating, 0)
+	err := gitPullAndUpdate(force, trigger)
+
+	atomic.StoreInt32(&isGitUpdating, 0)
+	if atomic.LoadInt32(&gitUpdatePending) == 1 {
+		go drainGitUpdateRequests()
+	}
+
+	return err
+}
+
+// RequestGitPullAndUpdate schedules a sync without waiting for it. If a sync is
+// already running the request is remembered and served by one extra run after it.
+// A replica that is not the leader records the request for the leader, which
+// polls it every syncRequestPollInterval.
+func RequestGitPullAndUpdate(trigger string) error {
+	if !leader.IsLeader() {
+		return mongo.SetSyncRequestToDB(trigger)
+	}
+
+	scheduleGitPullAndUpdate(trigger)
+	return nil
+}
+
+func scheduleGitPullAndUpdate(trigger string) {
+	pendingTrigger.Store(trigger)
+	atomic.StoreInt32(&gitUpdatePending, 1)
+	go drainGitUpdateRequests()
+}
+
+// syncRequestLoop serves on the leader the syncs requested on the other replicas.
+func syncRequestLoop() {
+	for {
+		time.Sleep(syncRequestPollInterval)
+		if !leader.IsLeader() {
+			continue
+		}
+
+		trigger, err := mongo.TakeSyncRequestFromDB()
+		if err != nil || trigger == "" {
+			continue
+		}
+		glog.Infof("sync requested on another replica, trigger:%s", trigger)
+		scheduleGitPullAndUpdate(trigger)
+	}
+}
+
+func drainGitUpdateRequests() {
+	if !atomic.CompareAndSwapInt32(&isGitUpdating, 0, 1) {
+		// the running sync picks up the pending request when it finishes
+		return
+	}
 
-	err := gitapp.Pull()
+	for atomic.SwapInt32(&gitUpdatePending, 0) == 1 {
+		trigger, _ := pendingTrigger.Load().(string)
+		err := gitPullAndUpdate(false, trigger)
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			glog.Warningf("requested git pull and update failed: %s", err.Error())
+		}
+	}
+
+	atomic.StoreInt32(&isGitUpdating, 0)
+	// a request may have arrived between the last swap and the release above
+	if atomic.LoadInt32(&gitUpdatePending) == 1 {
+		go drainGitUpdateRequests()
+	}
+}
+
+// gitPullAndUpdate pulls the catalog and re-ingests what changed since the last
+// recorded commit. force re-ingests every app even if nothing was pulled.
+func gitPullAndUpdate(force bool, trigger string) (err error) {
+	if !leader.IsLeader() {
+		return followLeader(force)
+	}
+
+	run := startSyncRun(trigger)
+	defer func() {
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			run.discard()
+			return
+		}
+		run.finish(err)
+	}()
+
+	oldHash, err := mongo.GetLastCommitHashFromDB()
+	if err != nil {
+		oldHash = ""
+	}
+
+	oldHeads, err := gitapp.GetLastSourceHashes()
+	if err != nil {
+		oldHash = ""
+	}
+
+	err = gitapp.Pull()
 	if err != nil {
 		if !errors.Is(err, git.NoErrAlreadyUpToDate) || !force {
 			glog.Warningf("git pull failed: %s", err.Error())
@@ -179,16 +438,40 @@ func GitPullAndUpdate(force bool) error {
 		}
 	}
 
-	err = gitapp.GetLastCommitHashAndUpdate()
+	err = run.leading()
+	if err != nil {
+		return err
+	}
+
+	newHash, err := gitapp.GetLastCommitHashAndUpdate()
 	if err != nil {
 		glog.Warningf("GetLastCommitHashAndUpdate err:%s", err.Error())
 		return err
 	}
 
-	return UpdateAppInfosToDB()
+	newHeads, _ := gitapp.GetLastSourceHashes()
+	run.update(func(r *models.SyncRun) {
+		r.OldCommit = oldHash
+		r.NewCommit = newHash
+		r.SourceCommits = newHeads
+		r.Full = true
+	})
 
-	//todo check app infos in mongo if not exist in local, then del it
-	//or del by lastCommitHash old
+	if force || oldHash == "" || oldHash == newHash {
+		return UpdateAppInfosToDB(run)
+	}
+
+	changes, err := gitapp.ChangedAppDirs(oldHeads)
+	if err != nil {
+		glog.Warningf("ChangedAppDirs %s..%s err:%s, fall back to full update", oldHash, newHash, err.Error())
+		return UpdateAppInfosToDB(run)
+	}
+
+	run.update(func(r *models.SyncRun) {
+		r.Full = false
+	})
+
+	return UpdateChangedAppInfosToDB(oldHash, newHash, changes, run)
 }
 
 func UpdateAppInfosToMongo(infos []*models.ApplicationInfoFullData) error {
@@ -218,24 +501,40 @@ outerLoop:
 		}
 	}
 
-	//todo delete expired information
-
 	return nil
 }
 
-func ReadAppInfo(dirName string) (*models.ApplicationInfoEntry, error) {
-	cfgFileName := path.Join(constants.AppGitLocalDir, dirName, constants.AppCfgFileName)
+// ReadAppInfo reads the app published under name from the source providing it.
+func ReadAppInfo(name string) (*models.ApplicationInfoEntry, error) {
+	appDir, exist := gitapp.FindAppDir(name)
+	if !exist {
+		return nil, fmt.Errorf("%s not exist", name)
+	}
+
+	appInfo, _, err := readAppInfoWithReport(appDir)
+	return appInfo, err
+}
+
+// readAppInfoWithReport reads the app info and validates the manifest. The
+// report is nil only when the manifest could not be read.
+func readAppInfoWithReport(appDir gitapp.AppDir) (*models.ApplicationInfoEntry, *models.ValidationReport, error) {
+	cfgFileName := path.Join(appDir.Path(), constants.AppCfgFileName)
 
 	f, err := os.Open(cfgFileName)
 	if err != nil {
 		glog.Warningf("%s", err.Error())
-		return nil, err
+		return nil, nil, err
 	}
+	defer f.Close()
 
 	cfgContent, err := io.ReadAll(f)
 	if err != nil {
 		glog.Warningf("%s", err.Error())
-		return nil, err
+		return nil, nil, err
+	}
+
+	newReport := func(issues ...models.ValidationIssue) *models.ValidationReport {
+		return validator.NewReport(appDir.AppName(), appDir.Source.Name, issues)
 	}
 
 	// First, attempt to parse the original configuration file
@@ -243,78 +542,91 @@ func ReadAppInfo(dirName string) (*models.ApplicationInfoEntry, error) {
 	if err = yaml.Unmarshal(cfgContent, &appCfg); err != nil {
 		// Check if the file contains template syntax
 		if strings.Contains(string(cfgContent), "{{") {
-			// Need to render templates to generate two different configurations for administrators and regular users
-			adminAppInfo, err := renderAppConfigWithTemplate(string(cfgContent), true)
-			if err != nil {
-				glog.Warningf("Failed to render admin application configuration: %s", err.Error())
-				return nil, err
-			}
+			// Render the template once per context, the primary rendering is the app info and the others are its variants
+			var issues []models.ValidationIssue
+			var primaryAppInfo *models.ApplicationInfoEntry
+			variantInfos := make(map[string]*models.ApplicationInfoEntry)
+			for i, ctx := range variant.Contexts() {
+				cfg, err := renderAppConfig(string(cfgContent), ctx)
+				if err != nil {
+					glog.Warningf("Failed to render application configuration for variant %s: %s", ctx.Name, err.Error())
+					return nil, newReport(validator.SyntaxIssue(fmt.Errorf("variant %s: %w", ctx.Name, err))), err
+				}
 
-			userAppInfo, err := renderAppConfigWithTemplate(string(cfgContent), false)
-			if err != nil {
-				glog.Warningf("Failed to render user application configuration: %s", err.Error())
-				return nil, err
+				issues = append(issues, validator.Validate(cfg)...)
+				info := appInfoParseQuantity(cfg.ToAppInfo())
+				if i == 0 {
+					primaryAppInfo = info
+				} else {
+					variantInfos[ctx.Name] = info
+				}
 			}
 
-			// Merge the two configurations to create an application information that contains both views
-			mergedAppInfo := mergeAppInfos(adminAppInfo, userAppInfo)
+			report := newReport(issues...)
 
-			// Continue processing the merged application information
-			disableCategories := getDisableCategories()
-			for _, categorie := range mergedAppInfo.Categories {
-				if strings.Contains(disableCategories, categorie) {
-					glog.Warningf("%s %s is disable", categorie, mergedAppInfo.AppID)
-					mergedAppInfo.AppLabels = append(mergedAppInfo.AppLabels, constants.DisableLabel)
-				}
-			}
+			// Merge the renderings to create an application information that contains every view
+			mergedAppInfo := mergeAppInfos(primaryAppInfo, variantInfos)
 
 			// Set i18n information
-			setI18nInfo(mergedAppInfo, path.Join(constants.AppGitLocalDir, dirName))
+			setI18nInfo(mergedAppInfo, appDir.Path())
 
 			// Check for special files
-			checkAppContainSpecialFile(mergedAppInfo, path.Join(constants.AppGitLocalDir, dirName))
+			checkAppContainSpecialFile(mergedAppInfo, appDir.Path())
+			setDeclaredLabels(mergedAppInfo, appDir.Path())
 
-			return mergedAppInfo, nil
+			setAppSource(mergedAppInfo, appDir)
+
+			return mergedAppInfo, report, nil
 		}
 
 		glog.Warningf("Failed to parse application configuration: %s", err.Error())
-		return nil, err
+		return nil, newReport(validator.SyntaxIssue(err)), err
 	}
 
 	// Normal non-template processing flow
+	report := newReport(validator.Validate(&appCfg)...)
 	appInfo := appInfoParseQuantity(appCfg.ToAppInfo())
 
-	disableCategories := getDisableCategories()
-	for _, categorie := range appInfo.Categories {
-		if strings.Contains(disableCategories, categorie) {
-			glog.Warningf("%s %s is disable", categorie, appInfo.AppID)
-			appInfo.AppLabels = append(appInfo.AppLabels, constants.DisableLabel)
-		}
-	}
-
 	// Set i18n information
-	setI18nInfo(appInfo, path.Join(constants.AppGitLocalDir, dirName))
+	setI18nInfo(appInfo, appDir.Path())
 
-	checkAppContainSpecialFile(appInfo, path.Join(constants.AppGitLocalDir, dirName))
+	checkAppContainSpecialFile(appInfo, appDir.Path())
+	setDeclaredLabels(appInfo, appDir.Path())
 
-	return appInfo, nil
+	setAppSource(appInfo, appDir)
+
+	return appInfo, report, nil
+}
+
+// setAppSource records the source of the app and applies the name prefix of the source.
+func setAppSource(appInfo *models.ApplicationInfoEntry, appDir gitapp.AppDir) {
+	prefix := appDir.Source.Prefix
+
+	appInfo.Name = prefix + appInfo.Name
+	appInfo.Source = appDir.Source.Name
+	for key, variant := range appInfo.Variants {
+		variant.Name = prefix + variant.Name
+		variant.Source = appDir.Source.Name
+		appInfo.Variants[key] = variant
+	}
 }
 
 // Render application configuration with templates
-func renderAppConfigWithTemplate(templateContent string, isAdmin bool) (*models.ApplicationInfoEntry, error) {
+func renderAppConfigWithTemplate(templateContent string, ctx variant.Context) (*models.ApplicationInfoEntry, error) {
+	appCfg, err := renderAppConfig(templateContent, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to application information entry
+	return appInfoParseQuantity(appCfg.ToAppInfo()), nil
+}
+
+// renderAppConfig renders a templated manifest with the values of a render context.
+func renderAppConfig(templateContent string, ctx variant.Context) (*models.AppConfiguration, error) {
 	// Create the values for template rendering
 	values := map[string]interface{}{
-		"Values": map[string]interface{}{
-			"admin": "admin", // Default admin username
-			"bfl": map[string]interface{}{
-				"username": func() string {
-					if isAdmin {
-						return "admin"
-					}
-					return "user"
-				}(), // Set different usernames based on role
-			},
-		},
+		"Values": ctx.Values,
 	}
 
 	// Create template with Sprig functions (includes semverCompare, toString, etc.)
@@ -338,28 +650,22 @@ func renderAppConfigWithTemplate(templateContent string, isAdmin bool) (*models.
 		return nil, err
 	}
 
-	// Convert to application information entry
-	appInfo := appInfoParseQuantity(appCfg.ToAppInfo())
-
-	return appInfo, nil
+	return &appCfg, nil
 }
 
-// Merge two application information, connecting different parts with special markers
-func mergeAppInfos(adminInfo, userInfo *models.ApplicationInfoEntry) *models.ApplicationInfoEntry {
-	// Use admin info as the primary application information
+// mergeAppInfos stores the renderings of the other contexts as named variants of the primary one.
+func mergeAppInfos(primaryInfo *models.ApplicationInfoEntry, variantInfos map[string]*models.ApplicationInfoEntry) *models.ApplicationInfoEntry {
 	mergedInfo := &models.ApplicationInfoEntry{}
-	*mergedInfo = *adminInfo
+	*mergedInfo = *primaryInfo
 
-	// Initialize the Variants map (if not already initialized)
-	if mergedInfo.Variants == nil {
-		mergedInfo.Variants = make(map[string]models.ApplicationInfoEntry)
+	if len(variantInfos) == 0 {
+		return mergedInfo
 	}
 
-	// Store the user (non-admin) application information in Variants
-	mergedInfo.Variants["user"] = *userInfo
-
-	// Record differences between the two views (logged at debug level if needed)
-	_ = reflect.DeepEqual(adminInfo, userInfo)
+	mergedInfo.Variants = make(map[string]models.ApplicationInfoEntry, len(variantInfos))
+	for name, info := range variantInfos {
+		mergedInfo.Variants[name] = *info
+	}
 
 	return mergedInfo
 }
@@ -492,39 +798,36 @@ func getConcurrency() int {
 	return DefaultConcurrency
 }
 
-func GetAppInfosFromGitDir(dir string, packageImage bool) (infos []*models.ApplicationInfoEntry, err error) {
-	charts, err := os.ReadDir(dir)
+func GetAppInfosFromGitDir(packageImage bool) (infos []*models.ApplicationInfoEntry, err error) {
+	appDirs, err := gitapp.ListAppDirs()
 	if err != nil {
-		glog.Warningf("read dir %s error: %s", dir, err.Error())
 		return nil, err
 	}
 
-	// Configure docker image source once before processing all apps
-	err = configureDockerImageSource()
-	if err != nil {
-		glog.Warningf("Failed to configure docker image source: %v", err)
-		// Continue processing even if image source configuration fails
-	}
+	// Use parallel processing for better performance
+	return GetAppInfosFromGitDirParallel(appDirs, packageImage)
+}
 
-	// Collect valid app directories
-	var appDirs []string
-	for _, c := range charts {
-		if !c.IsDir() || strings.HasPrefix(c.Name(), ".") {
-			continue
-		}
-		appDirs = append(appDirs, c.Name())
-	}
+// GetAppInfosFromGitDirParallel processes apps in parallel using worker pool pattern
+func GetAppInfosFromGitDirParallel(appDirs []gitapp.AppDir, packageImage bool) ([]*models.ApplicationInfoEntry, error) {
+	infos, _, _, err := processAppDirs(appDirs, packageImage)
+	return infos, err
+}
 
+// processAppDirs processes apps in parallel and also returns the apps that
+// failed and the validation reports of the manifests.
+func processAppDirs(appDirs []gitapp.AppDir, packageImage bool) ([]*models.ApplicationInfoEntry, []models.SyncAppFailure, []*models.ValidationReport, error) {
 	if len(appDirs) == 0 {
-		return []*models.ApplicationInfoEntry{}, nil
+		return []*models.ApplicationInfoEntry{}, nil, nil, nil
 	}
 
-	// Use parallel processing for better performance
-	return GetAppInfosFromGitDirParallel(appDirs, packageImage)
-}
+	// Configure docker image source once before processing all apps
+	err := configureDockerImageSource()
+	if err != nil {
+		glog.Warningf("Failed to configure docker image source: %v", err)
+		// Continue processing even if image source configuration fails
+	}
 
-// GetAppInfosFromGitDirParallel processes apps in parallel using worker pool pattern
-func GetAppInfosFromGitDirParallel(appDirs []string, packageImage bool) ([]*models.ApplicationInfoEntry, error) {
 	concurrency := getConcurrency()
 	// Ensure concurrency doesn't exceed the number of apps
 	if concurrency > len(appDirs) {
@@ -533,7 +836,7 @@ func GetAppInfosFromGitDirParallel(appDirs []string, packageImage bool) ([]*mode
 	glog.Infof("Processing %d apps with concurrency %d", len(appDirs), concurrency)
 
 	// Create channels for work distribution and result collection
-	jobs := make(chan string, len(appDirs))
+	jobs := make(chan gitapp.AppDir, len(appDirs))
 	results := make(chan *appProcessResult, len(appDirs))
 
 	// Populate jobs channel
@@ -561,13 +864,19 @@ func GetAppInfosFromGitDirParallel(appDirs []string, packageImage bool) ([]*mode
 	// Collect results
 	var infos []*models.ApplicationInfoEntry
 	var errors []error
+	var failed []models.SyncAppFailure
+	var reports []*models.ValidationReport
 	successCount := 0
 	failureCount := 0
 
 	for result := range results {
+		if result.report != nil {
+			reports = append(reports, result.report)
+		}
 		if result.err != nil {
 			failureCount++
 			errors = append(errors, fmt.Errorf("app %s: %w", result.appName, result.err))
+			failed = append(failed, models.SyncAppFailure{Name: result.appName, Error: result.err.Error()})
 			glog.Warningf("Failed to process app %s: %v", result.appName, result.err)
 		} else if result.appInfo != nil {
 			successCount++
@@ -579,37 +888,84 @@ func GetAppInfosFromGitDirParallel(appDirs []string, packageImage bool) ([]*mode
 
 	// Return error if all apps failed, but still return partial results
 	if len(infos) == 0 && len(errors) > 0 {
-		return nil, fmt.Errorf("all apps failed to process: %v", errors[0])
+		return nil, failed, reports, fmt.Errorf("all apps failed to process: %v", errors[0])
+	}
+
+	return resolveNameCollisions(infos), failed, reports, nil
+}
+
+func appNames(infos []*models.ApplicationInfoEntry) []string {
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name)
 	}
 
-	return infos, nil
+	return names
+}
+
+// resolveNameCollisions keeps one app per name when several sources provide it,
+// the app of the source with the highest priority wins.
+func resolveNameCollisions(infos []*models.ApplicationInfoEntry) []*models.ApplicationInfoEntry {
+	winners := make(map[string]*models.ApplicationInfoEntry)
+	var names []string
+	for _, info := range infos {
+		cur, ok := winners[info.Name]
+		if !ok {
+			winners[info.Name] = info
+			names = append(names, info.Name)
+			continue
+		}
+
+		winner := cur
+		if gitapp.GetSource(info.Source).Outranks(gitapp.GetSource(cur.Source)) {
+			winner = info
+		}
+		glog.Warningf("app %s is provided by sources %s and %s, using the one from %s", info.Name, cur.Source, info.Source, winner.Source)
+		winners[info.Name] = winner
+	}
+
+	resolved := make([]*models.ApplicationInfoEntry, 0, len(names))
+	for _, name := range names {
+		resolved = append(resolved, winners[name])
+	}
+
+	return resolved
 }
 
 // appProcessResult represents the result of processing a single app
 type appProcessResult struct {
 	appName string
 	appInfo *models.ApplicationInfoEntry
+	report  *models.ValidationReport
 	err     error
 }
 
 // processAppWorker is a worker function that processes apps from the jobs channel
-func processAppWorker(jobs <-chan string, results chan<- *appProcessResult, packageImage bool) {
-	for appName := range jobs {
+func processAppWorker(jobs <-chan gitapp.AppDir, results chan<- *appProcessResult, packageImage bool) {
+	for appDir := range jobs {
 		result := &appProcessResult{
-			appName: appName,
+			appName: appDir.AppName(),
 		}
 
 		// read app info from chart
-		appInfo, err := ReadAppInfo(appName)
+		appInfo, report, err := readAppInfoWithReport(appDir)
+		result.report = report
 		if err != nil {
 			result.err = fmt.Errorf("ReadAppInfo failed: %w", err)
 			results <- result
 			continue
 		}
 
+		// apps with manifest errors are not published
+		if !report.Valid {
+			result.err = fmt.Errorf("%w: %s: %s", ErrInvalidManifest, report.Errors[0].Field, report.Errors[0].Message)
+			results <- result
+			continue
+		}
+
 		if packageImage {
 			// DownloadImagesInfo
-			err = images.DownloadImagesInfo(path.Join(constants.AppGitLocalDir, appName))
+			err = images.DownloadImagesInfo(appDir.Path())
 			if err != nil {
 				result.err = fmt.Errorf("DownloadImagesInfo failed: %w", err)
 				results <- result
@@ -618,7 +974,7 @@ func processAppWorker(jobs <-chan string, results chan<- *appProcessResult, pack
 		}
 
 		// helm package
-		appInfo.ChartName, err = helmPackage(appName)
+		appInfo.ChartName, err = helmPackage(appDir)
 		if err != nil {
 			result.err = fmt.Errorf("helm package failed: %w", err)
 			results <- result
@@ -626,32 +982,31 @@ func processAppWorker(jobs <-chan string, results chan<- *appProcessResult, pack
 		}
 
 		// get git info
-		getGitInfosByName(appInfo, appName)
+		getGitInfosByName(appInfo, appDir)
 
 		result.appInfo = appInfo
 		results <- result
 	}
 }
 
-func getGitInfosByName(appInfo *models.ApplicationInfoEntry, name string) {
+func getGitInfosByName(appInfo *models.ApplicationInfoEntry, appDir gitapp.AppDir) {
 	var err error
 	appInfo.LastCommitHash, err = gitapp.GetLastHash()
 	if err != nil {
 		glog.Warningf("GetLastHash error: %s", err.Error())
 	}
 
-	appInfo.CreateTime, err = gitapp.GetCreateTimeSecond(constants.AppGitLocalDir, name)
+	appInfo.CreateTime, appInfo.UpdateTime, err = gitapp.GetAppDirTimes(appDir)
 	if err != nil {
-		glog.Warningf("GetCreateTimeSecond error: %s", err.Error())
+		glog.Warningf("GetAppDirTimes %s error: %s", appDir.Path(), err.Error())
 	}
+}
 
-	appInfo.UpdateTime, err = gitapp.GetLastUpdateTimeSecond(constants.AppGitLocalDir, name)
+func helmPackage(appDir gitapp.AppDir) (string, error) {
+	fileName, err := helm.PackageHelm(appDir.Path(), appDir.Source.ChartDir())
 	if err != nil {
-		glog.Warningf("GetLastUpdateTimeSecond error: %s", err.Error())
+		return "", err
 	}
-}
 
-func helmPackage(name string) (string, error) {
-	src := path.Join(constants.AppGitLocalDir, name)
-	return helm.PackageHelm(src, constants.AppGitZipLocalDir)
+	return appDir.Source.ChartName(fileName), nil
 }
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/models"
+	"os"
+	"strings"
+	"time"
+)
+
+// DisableCategoriesEnv lists the categories whose apps are hidden, comma
+// separated. It only seeds the category policies, later changes go
+// through the admin API.
+const DisableCategoriesEnv = "DISABLE_CATEGORIES"
+
+// seedCategoryPolicies hides the apps of the categories of DISABLE_CATEGORIES
+// when no category policy was set yet. They stay out of list, search and info,
+// as they did when the ingestion deleted them before the policies.
+func seedCategoryPolicies() error {
+	var policies []*models.CategoryPolicy
+	for _, category := range strings.Split(os.Getenv(DisableCategoriesEnv), ",") {
+		category = strings.TrimSpace(category)
+		if category == "" {
+			continue
+		}
+		policies = append(policies, &models.CategoryPolicy{
+			Category:   category,
+			Action:     models.CategoryActionHide,
+			UpdateTime: time.Now().Unix(),
+		})
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+
+	return mongo.SeedCategoryPolicies(policies)
+}
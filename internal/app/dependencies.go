@@ -0,0 +1,110 @@
+package app
+
+import (
+	"app-store-server/internal/mongo"
+	"app-store-server/internal/resolver"
+	"app-store-server/pkg/models"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/golang/glog"
+)
+
+var (
+	catalogMu sync.Mutex
+	// catalog caches the apps dependencies are resolved from, until the catalog generation changes
+	catalog           resolver.Catalog
+	catalogGeneration int64
+)
+
+// dependencyCatalog returns the apps of the last completed ingestion. The
+// generation is bumped once the app infos are written, a catalog read during
+// an ingestion is replaced when it completes.
+func dependencyCatalog() (resolver.Catalog, error) {
+	generation, err := mongo.GetCatalogGeneration()
+	if err != nil {
+		return nil, err
+	}
+
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	if catalog != nil && generation == catalogGeneration {
+		return catalog, nil
+	}
+
+	apps, _, err := mongo.GetAllAppLists(0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog = resolver.NewCatalog(apps)
+	catalogGeneration = generation
+
+	return catalog, nil
+}
+
+// catalogChanged tells the replicas the app infos changed, their cached
+// catalogs are reloaded.
+func catalogChanged() {
+	err := mongo.BumpCatalogGeneration()
+	if err != nil {
+		glog.Warningf("BumpCatalogGeneration err:%s", err.Error())
+	}
+}
+
+// ResolveDependencies picks the versions of an app and of the apps it depends
+// on for the system version.
+func ResolveDependencies(name, systemVersion string) (*models.DependencyResolution, error) {
+	v, err := semver.NewVersion(systemVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := dependencyCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	return resolver.Resolve(c, name, v)
+}
+
+// checkDependencyGraph records on the run the dependencies of the ingested
+// catalog that cannot be resolved.
+func checkDependencyGraph(run *syncRecorder) {
+	apps, _, err := mongo.GetAllAppLists(0, 0)
+	if err != nil {
+		glog.Warningf("check dependency graph err:%s", err.Error())
+		return
+	}
+
+	problems := resolver.CheckCatalog(resolver.NewCatalog(apps))
+	for _, p := range problems {
+		glog.Warningf("app %s dependency %s %s: %s", p.App, p.Dependency, p.Kind, p.Message)
+	}
+
+	run.update(func(r *models.SyncRun) {
+		r.DependencyProblems = problems
+	})
+}
+
+// CheckInstall tells whether a version of an app, the newest running on the
+// system version when version is empty, can be installed next to the installed apps.
+func CheckInstall(name, version, systemVersion string, installed map[string]string) (*models.InstallCheckRes, error) {
+	v, err := semver.NewVersion(systemVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := dependencyCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	candidate, err := c.Pick(name, version, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolver.CheckInstall(c, candidate, installed), nil
+}
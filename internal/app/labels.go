@@ -0,0 +1,217 @@
+package app
+
+import (
+	"app-store-server/internal/constants"
+	"app-store-server/internal/es"
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/models"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v3"
+)
+
+// labelsFile is the labels.yaml of an app, e.g.
+//
+//	labels:
+//	- name: suspend
+//	  reason: the upstream image is being rebuilt
+//	  effective: 2025-03-01
+//	  expires: 2025-04-01T12:00:00Z
+//	  versions: "<1.2.0"
+type labelsFile struct {
+	Labels []struct {
+		Name      string `yaml:"name"`
+		Reason    string `yaml:"reason"`
+		Effective string `yaml:"effective"`
+		Expires   string `yaml:"expires"`
+		Versions  string `yaml:"versions"`
+	} `yaml:"labels"`
+}
+
+// parseLabelTime reads a date or an RFC 3339 time of labels.yaml, 0 when empty.
+func parseLabelTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("time %q is neither a date nor an RFC 3339 time", value)
+}
+
+// readAppLabels returns the labels of labels.yaml applying to version. A
+// broken label is logged and left out, the others still apply.
+func readAppLabels(appDir, version string) ([]models.AppLabel, error) {
+	data, err := os.ReadFile(path.Join(appDir, constants.LabelsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f labelsFile
+	err = yaml.Unmarshal(data, &f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", constants.LabelsFile, err)
+	}
+
+	var labels []models.AppLabel
+	for i, l := range f.Labels {
+		if l.Name == "" {
+			glog.Warningf("%s of %s: label %d has no name", constants.LabelsFile, appDir, i)
+			continue
+		}
+
+		label := models.AppLabel{
+			Name:     l.Name,
+			Reason:   l.Reason,
+			Versions: l.Versions,
+		}
+		label.EffectiveAt, err = parseLabelTime(l.Effective)
+		if err == nil {
+			label.ExpireAt, err = parseLabelTime(l.Expires)
+		}
+		if err != nil {
+			glog.Warningf("%s of %s: label %s: %s", constants.LabelsFile, appDir, l.Name, err.Error())
+			continue
+		}
+
+		if l.Versions != "" {
+			applies, err := versionMatches(version, l.Versions)
+			if err != nil {
+				glog.Warningf("%s of %s: label %s: %s", constants.LabelsFile, appDir, l.Name, err.Error())
+				continue
+			}
+			if !applies {
+				continue
+			}
+		}
+
+		labels = append(labels, label)
+	}
+
+	return labels, nil
+}
+
+func versionMatches(version, versions string) (bool, error) {
+	constraint, err := semver.NewConstraint(versions)
+	if err != nil {
+		return false, fmt.Errorf("versions %q: %w", versions, err)
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, fmt.Errorf("app version %q: %w", version, err)
+	}
+
+	return constraint.Check(v), nil
+}
+
+// setDeclaredLabels adds the labels of labels.yaml to the app, the active ones
+// also to its AppLabels next to the marker file labels.
+func setDeclaredLabels(info *models.ApplicationInfoEntry, appDir string) {
+	labels, err := readAppLabels(appDir, info.Version)
+	if err != nil {
+		glog.Warningf("%s %s", info.Name, err.Error())
+		return
+	}
+
+	info.Labels = labels
+	info.AppLabels = activeLabelNames(info.AppLabels, labels, time.Now().Unix())
+}
+
+// activeLabelNames returns appLabels with the declared labels replaced by the
+// ones active at now. Labels not declared, like the marker file ones, are kept.
+func activeLabelNames(appLabels []string, labels []models.AppLabel, now int64) []string {
+	declared := make(map[string]bool)
+	for _, l := range labels {
+		declared[l.Name] = true
+	}
+
+	names := []string{}
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range appLabels {
+		if !declared[name] {
+			add(name)
+		}
+	}
+	for _, l := range labels {
+		if l.ActiveAt(now) {
+			add(l.Name)
+		}
+	}
+
+	return names
+}
+
+func sameLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// refreshAppLabels updates the AppLabels of the apps whose declared labels
+// became effective or expired since the app was stored, so the label filters
+// follow the time windows between catalog changes.
+func refreshAppLabels() error {
+	infos, err := mongo.GetAppInfosWithLabels()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	defer func() {
+		if changed {
+			catalogChanged()
+		}
+	}()
+
+	now := time.Now().Unix()
+	for _, info := range infos {
+		latest := info.History["latest"]
+		names := activeLabelNames(latest.AppLabels, latest.Labels, now)
+		if sameLabels(names, latest.AppLabels) {
+			continue
+		}
+
+		updated, err := mongo.SetAppLabels(info.Name, latest.Version, names)
+		if err != nil {
+			glog.Warningf("mongo.SetAppLabels %s err:%s", info.Name, err.Error())
+			continue
+		}
+		glog.Infof("labels of %s changed from %v to %v", info.Name, latest.AppLabels, names)
+		changed = true
+
+		err = es.UpsertAppInfoToDb(updated)
+		if err != nil {
+			glog.Warningf("es.UpsertAppInfoToDb %s err:%s", info.Name, err.Error())
+		}
+	}
+
+	return nil
+}
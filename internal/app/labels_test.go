@@ -0,0 +1,127 @@
+package app
+
+import (
+	"app-store-server/internal/constants"
+	"app-store-server/pkg/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const labelsYAML = `labels:
+- name: suspend
+  reason: the upstream image is being rebuilt
+  effective: 2025-03-01
+  expires: 2025-04-01T12:00:00Z
+- name: deprecated
+  versions: "<1.2.0"
+- name: beta
+  versions: ">=2.0.0"
+- name: featured
+- name: broken-time
+  expires: next week
+- name: broken-versions
+  versions: "not a constraint"
+- reason: no name
+`
+
+func writeLabels(t *testing.T, content string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, constants.LabelsFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestReadAppLabels(t *testing.T) {
+	dir := writeLabels(t, labelsYAML)
+
+	labels, err := readAppLabels(dir, "1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.AppLabel{
+		{
+			Name:        "suspend",
+			Reason:      "the upstream image is being rebuilt",
+			EffectiveAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Unix(),
+			ExpireAt:    time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC).Unix(),
+		},
+		{Name: "deprecated", Versions: "<1.2.0"},
+		{Name: "featured"},
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %+v, want %+v", labels, want)
+	}
+
+	labels, err = readAppLabels(dir, "2.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	if want := []string{"suspend", "beta", "featured"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("labels of 2.1.0 = %v, want %v", names, want)
+	}
+}
+
+func TestReadAppLabelsFile(t *testing.T) {
+	if labels, err := readAppLabels(t.TempDir(), "1.0.0"); labels != nil || err != nil {
+		t.Errorf("without labels.yaml = %v, %v", labels, err)
+	}
+	if _, err := readAppLabels(writeLabels(t, "labels: {"), "1.0.0"); err == nil {
+		t.Error("no error for a labels.yaml that does not parse")
+	}
+}
+
+func TestActiveLabelNames(t *testing.T) {
+	labels := []models.AppLabel{
+		{Name: "suspend", EffectiveAt: 2000, ExpireAt: 3000},
+		{Name: "featured"},
+	}
+
+	tests := []struct {
+		name      string
+		appLabels []string
+		now       int64
+		want      []string
+	}{
+		{name: "before the window", appLabels: []string{"remove", "suspend"}, now: 1999, want: []string{"remove", "featured"}},
+		{name: "in the window", appLabels: []string{"remove"}, now: 2000, want: []string{"remove", "suspend", "featured"}},
+		{name: "expired", appLabels: []string{"suspend", "featured"}, now: 3000, want: []string{"featured"}},
+		{name: "declared labels in file order", appLabels: []string{"featured"}, now: 2500, want: []string{"suspend", "featured"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := activeLabelNames(tt.appLabels, labels, tt.now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("labels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLabelTime(t *testing.T) {
+	tests := map[string]int64{
+		"":                          0,
+		"2025-03-01":                time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		"2025-03-01T08:00:00+08:00": time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Unix(),
+	}
+	for value, want := range tests {
+		if got, err := parseLabelTime(value); err != nil || got != want {
+			t.Errorf("parseLabelTime(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+
+	if _, err := parseLabelTime("03/01/2025"); err == nil {
+		t.Error("no error for a time in another layout")
+	}
+}
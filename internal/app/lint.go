@@ -0,0 +1,93 @@
+package app
+
+import (
+	"app-store-server/internal/gitapp"
+	"app-store-server/internal/helm"
+	"app-store-server/internal/images"
+	"app-store-server/pkg/models"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LintResult is what the server makes of an app directory.
+type LintResult struct {
+	Dir     string                   `json:"dir"`
+	Name    string                   `json:"name"`
+	Version string                   `json:"version"`
+	Report  *models.ValidationReport `json:"report"`
+	Chart   string                   `json:"chart,omitempty"`
+	Images  []string                 `json:"images"`
+	// Errors are the failures of the steps after validation, packaging and image extraction
+	Errors []string `json:"errors"`
+}
+
+// Failed reports whether the server would refuse to publish the app.
+func (r *LintResult) Failed() bool {
+	return len(r.Errors) > 0 || r.Report == nil || !r.Report.Valid
+}
+
+// LintAppDir runs the manifest parsing, template rendering, validation, helm
+// packaging and image extraction of the sync on one app directory, without
+// mongo, ES or network.
+func LintAppDir(dir string) (*LintResult, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	// read the directory like an app of a local source
+	appDir := gitapp.AppDir{
+		Source: &gitapp.Source{
+			Name: "lint",
+			Type: gitapp.SourceTypeLocal,
+			Path: filepath.Dir(dir),
+		},
+		Name: filepath.Base(dir),
+	}
+
+	result := &LintResult{
+		Dir:    dir,
+		Name:   appDir.Name,
+		Images: []string{},
+		Errors: []string{},
+	}
+
+	appInfo, report, err := readAppInfoWithReport(appDir)
+	if report == nil {
+		return nil, err
+	}
+	result.Report = report
+	if appInfo != nil {
+		if appInfo.Name != "" {
+			result.Name = appInfo.Name
+		}
+		result.Version = appInfo.Version
+	}
+
+	chartDir, err := os.MkdirTemp("", "lint-chart")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(chartDir)
+
+	result.Chart, err = helm.PackageHelm(dir, chartDir)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("helm package failed: %s", err.Error()))
+	}
+
+	result.Images, err = images.ExtractImages(dir)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("image extraction failed: %s", err.Error()))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"app-store-server/internal/constants"
+	"app-store-server/internal/es"
+	"app-store-server/internal/gitapp"
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/models"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// RemovalGracePeriodEnv is how long an app removed from the catalog is kept
+	// before it is deleted, a Go duration like "168h"
+	RemovalGracePeriodEnv     = "APP_REMOVAL_GRACE_PERIOD"
+	DefaultRemovalGracePeriod = 7 * 24 * time.Hour
+)
+
+func getRemovalGracePeriod() time.Duration {
+	d, err := time.ParseDuration(os.Getenv(RemovalGracePeriodEnv))
+	if err == nil && d >= 0 {
+		return d
+	}
+
+	return DefaultRemovalGracePeriod
+}
+
+// reconcileRemovedApps tombstones the apps whose directory is gone from every
+// source and deletes their ES docs and validation reports. Apps removed longer than the grace period
+// ago are deleted, together with the charts no app references anymore.
+// ingested holds the apps of this run, they are present even if a failed
+// read left them out of the directory listing.
+func reconcileRemovedApps(ingested []*models.ApplicationInfoEntry) ([]string, error) {
+	appDirs, err := gitapp.ListAppDirs()
+	if err != nil {
+		return nil, err
+	}
+	if len(appDirs) == 0 {
+		// an empty checkout is more likely broken than emptied on purpose
+		glog.Warningf("no app directories found, skip removing apps")
+		return nil, nil
+	}
+
+	dirNames := make([]string, 0, len(appDirs))
+	for _, d := range appDirs {
+		dirNames = append(dirNames, d.AppName())
+	}
+	hash, err := gitapp.GetLastHash()
+	if err != nil {
+		return nil, err
+	}
+	listed, err := mongo.GetListedAppInfos()
+	if err != nil {
+		return nil, err
+	}
+	present := presentApps(dirNames, listed, hash, ingested)
+
+	// reports are keyed by directory like the ingestion writes them, the
+	// manifest name of an app can differ and is unknown when it did not parse
+	err = mongo.DeleteValidationReportsExcept(dirNames)
+	if err != nil {
+		glog.Warningf("DeleteValidationReportsExcept err:%s", err.Error())
+	}
+
+	now := time.Now()
+	removed, err := mongo.TombstoneAppInfos(present, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range removed {
+		names = append(names, info.Name)
+		err = es.DeleteAppInfoFromDb(info.Id)
+		if err != nil {
+			glog.Warningf("es.DeleteAppInfoFromDb %s err:%s", info.Name, err.Error())
+		}
+	}
+	if len(names) > 0 {
+		glog.Infof("apps removed from the catalog: %v", names)
+	}
+
+	grace := getRemovalGracePeriod()
+	purged, err := mongo.PurgeAppInfos(now.Add(-grace).Unix())
+	if err != nil {
+		glog.Warningf("PurgeAppInfos err:%s", err.Error())
+		return names, nil
+	}
+	for _, info := range purged {
+		glog.Infof("app %s deleted, removed at %d", info.Name, info.RemovedAt)
+	}
+
+	err = removeOrphanCharts(now.Add(-grace))
+	if err != nil {
+		glog.Warningf("removeOrphanCharts err:%s", err.Error())
+	}
+
+	return names, nil
+}
+
+// presentApps returns the names of the apps still in the catalog at hash: the
+// app directories, the apps ingested by this run and the stored apps carried
+// forward to hash. The manifest name of an app may differ from its directory,
+// an incremental run only knows it for the apps it re-ingested.
+func presentApps(dirNames []string, stored []*models.ApplicationInfoFullData, hash string, ingested []*models.ApplicationInfoEntry) []string {
+	present := append(append([]string{}, dirNames...), appNames(ingested)...)
+	for _, info := range stored {
+		if info.History["latest"].LastCommitHash == hash {
+			present = append(present, info.Name)
+		}
+	}
+
+	return present
+}
+
+// removeOrphanCharts deletes the chart archives no app version references.
+// Archives written after notAfter are kept, they may belong to an app whose
+// info is not stored yet.
+func removeOrphanCharts(notAfter time.Time) error {
+	referenced, err := mongo.GetChartNames()
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(constants.AppGitZipLocalDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".tgz") {
+			return nil
+		}
+
+		name, err := filepath.Rel(constants.AppGitZipLocalDir, p)
+		if err != nil || referenced[filepath.ToSlash(name)] {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.ModTime().After(notAfter) {
+			return nil
+		}
+
+		err = os.Remove(p)
+		if err != nil {
+			glog.Warningf("remove chart %s err:%s", p, err.Error())
+			return nil
+		}
+		glog.Infof("removed orphan chart %s", name)
+
+		return nil
+	})
+}
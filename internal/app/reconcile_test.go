@@ -0,0 +1,58 @@
+package app
+
+import (
+	"app-store-server/pkg/models"
+	"sort"
+	"testing"
+)
+
+func storedApp(name, hash string) *models.ApplicationInfoFullData {
+	return &models.ApplicationInfoFullData{
+		Name: name,
+		History: map[string]models.ApplicationInfoEntry{
+			"latest": {Name: name, LastCommitHash: hash},
+		},
+	}
+}
+
+func TestPresentApps(t *testing.T) {
+	stored := []*models.ApplicationInfoFullData{
+		// carried forward, its manifest name differs from its directory "notes"
+		storedApp("notes-app", "new"),
+		storedApp("files", "new"),
+		// its directory is gone, it was not carried forward
+		storedApp("gone-app", "old"),
+		// re-ingested under a new manifest name
+		storedApp("renamed", "old"),
+	}
+	dirNames := []string{"notes", "files", "renamed-dir"}
+	ingested := []*models.ApplicationInfoEntry{{Name: "renamed-app"}}
+
+	present := make(map[string]bool)
+	for _, name := range presentApps(dirNames, stored, "new", ingested) {
+		present[name] = true
+	}
+
+	for _, name := range []string{"notes-app", "files", "renamed-app", "notes"} {
+		if !present[name] {
+			t.Errorf("%s is not present", name)
+		}
+	}
+	for _, name := range []string{"gone-app", "renamed"} {
+		if present[name] {
+			t.Errorf("%s is present", name)
+		}
+	}
+}
+
+func TestPresentAppsFullRun(t *testing.T) {
+	// a full run re-ingests every app at the new commit
+	stored := []*models.ApplicationInfoFullData{storedApp("notes-app", "old")}
+	ingested := []*models.ApplicationInfoEntry{{Name: "notes-app"}}
+
+	present := presentApps([]string{"notes"}, stored, "new", ingested)
+	sort.Strings(present)
+	if len(present) != 2 || present[0] != "notes" || present[1] != "notes-app" {
+		t.Errorf("present = %v", present)
+	}
+}
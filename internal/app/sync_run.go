@@ -0,0 +1,246 @@
+package app
+
+import (
+	"app-store-server/internal/gitapp"
+	"app-store-server/internal/leader"
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/models"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// SyncRunHistoryEnv is the number of sync runs kept in mongo
+	SyncRunHistoryEnv     = "SYNC_RUN_HISTORY"
+	DefaultSyncRunHistory = 200
+)
+
+var (
+	// syncRunsMu guards the state of the recorders and currentRun
+	syncRunsMu sync.Mutex
+	// persistMu keeps the writes of a run in the order of its changes
+	persistMu sync.Mutex
+
+	currentRun    *syncRecorder
+	lastCheckTime int64
+)
+
+// syncRecorder records a sync run. Runs that find nothing to do are not kept,
+// they only update the last check time. The methods accept a nil recorder.
+type syncRecorder struct {
+	run     models.SyncRun
+	started time.Time
+	// keep is set once the run is worth recording, its changes are persisted from then on
+	keep bool
+	// term is the leadership the run started in, it writes only while it lasts
+	term uint64
+	// rebuildIndex fills a new ES index instead of updating the one searches use
+	rebuildIndex bool
+}
+
+func getSyncRunHistory() int64 {
+	var history int64
+	_, err := fmt.Sscanf(os.Getenv(SyncRunHistoryEnv), "%d", &history)
+	if err == nil && history > 0 {
+		return history
+	}
+
+	return DefaultSyncRunHistory
+}
+
+func startSyncRun(trigger string) *syncRecorder {
+	now := time.Now()
+	r := &syncRecorder{
+		run: models.SyncRun{
+			Id:         primitive.NewObjectID().Hex(),
+			Trigger:    trigger,
+			Status:     models.SyncStatusRunning,
+			StartTime:  now.Unix(),
+			ESPhase:    models.SyncPhase{Status: models.SyncStatusSkipped},
+			ImagePhase: models.SyncPhase{Status: models.SyncStatusSkipped},
+		},
+		started: now,
+		term:    leader.Term(),
+	}
+
+	syncRunsMu.Lock()
+	currentRun = r
+	lastCheckTime = now.Unix()
+	syncRunsMu.Unlock()
+
+	return r
+}
+
+// leading returns leader.ErrNotLeader once the replica lost the leadership
+// the run started in, another replica may be writing the catalog since.
+func (r *syncRecorder) leading() error {
+	if r == nil || leader.Leading(r.term) {
+		return nil
+	}
+
+	return leader.ErrNotLeader
+}
+
+func (r *syncRecorder) update(fn func(run *models.SyncRun)) {
+	if r == nil {
+		return
+	}
+
+	syncRunsMu.Lock()
+	fn(&r.run)
+	keep := r.keep
+	syncRunsMu.Unlock()
+
+	if keep {
+		r.persist()
+	}
+}
+
+func (r *syncRecorder) persist() {
+	persistMu.Lock()
+	defer persistMu.Unlock()
+
+	run := r.snapshot()
+	err := mongo.UpsertSyncRun(&run)
+	if err != nil {
+		glog.Warningf("UpsertSyncRun %s err:%s", run.Id, err.Error())
+	}
+}
+
+func (r *syncRecorder) snapshot() models.SyncRun {
+	syncRunsMu.Lock()
+	defer syncRunsMu.Unlock()
+
+	return r.run
+}
+
+// finish ends the ingestion part of the run, the ES and image phases may still
+// be running and update the run when they end.
+func (r *syncRecorder) finish(err error) {
+	if r == nil {
+		return
+	}
+
+	end := time.Now()
+	syncRunsMu.Lock()
+	r.run.EndTime = end.Unix()
+	r.run.Duration = end.Sub(r.started).Milliseconds()
+	switch {
+	case err != nil:
+		r.run.Status = models.SyncStatusFailed
+		r.run.Error = err.Error()
+	case r.run.Status == models.SyncStatusRunning:
+		r.run.Status = models.SyncStatusSuccess
+	}
+	r.keep = true
+	if currentRun == r {
+		currentRun = nil
+	}
+	syncRunsMu.Unlock()
+
+	r.persist()
+	run := r.snapshot()
+	glog.Infof("sync run %s %s in %dms, %s..%s", run.Id, run.Status, run.Duration, run.OldCommit, run.NewCommit)
+
+	err = mongo.TrimSyncRuns(getSyncRunHistory())
+	if err != nil {
+		glog.Warningf("TrimSyncRuns err:%s", err.Error())
+	}
+}
+
+// discard drops a run that found nothing to do.
+func (r *syncRecorder) discard() {
+	if r == nil {
+		return
+	}
+
+	syncRunsMu.Lock()
+	defer syncRunsMu.Unlock()
+	if currentRun == r {
+		currentRun = nil
+	}
+}
+
+// skipSyncRun marks a run whose ingestion was skipped because another one was in progress.
+func skipSyncRun(r *syncRecorder) {
+	r.update(func(run *models.SyncRun) {
+		run.Status = models.SyncStatusSkipped
+		run.Error = "app info update of another run is in progress"
+	})
+}
+
+func (r *syncRecorder) startPhase(phase func(run *models.SyncRun) *models.SyncPhase) {
+	r.update(func(run *models.SyncRun) {
+		*phase(run) = models.SyncPhase{
+			Status:    models.SyncStatusRunning,
+			StartTime: time.Now().Unix(),
+		}
+	})
+}
+
+func (r *syncRecorder) endPhase(phase func(run *models.SyncRun) *models.SyncPhase, failed []models.SyncAppFailure, err error) {
+	r.update(func(run *models.SyncRun) {
+		p := phase(run)
+		p.EndTime = time.Now().Unix()
+		p.Failed = failed
+		p.Status = models.SyncStatusSuccess
+		if err != nil {
+			p.Status = models.SyncStatusFailed
+			p.Error = err.Error()
+		}
+	})
+}
+
+func esPhase(run *models.SyncRun) *models.SyncPhase {
+	return &run.ESPhase
+}
+
+func imagePhase(run *models.SyncRun) *models.SyncPhase {
+	return &run.ImagePhase
+}
+
+// GetSyncStatus returns the live sync state together with the last recorded runs.
+func GetSyncStatus() (*models.SyncStatus, error) {
+	status := &models.SyncStatus{
+		Replica:         leader.Identity(),
+		IsLeader:        leader.IsLeader(),
+		Running:         atomic.LoadInt32(&isGitUpdating) == 1,
+		Pending:         atomic.LoadInt32(&gitUpdatePending) == 1,
+		Ingesting:       atomic.LoadInt32(&isAppInfoUpdating) == 1,
+		ProcessingImage: atomic.LoadInt32(&isImageProcessing) == 1,
+		Rejections:      gitapp.VerifyFailures(),
+	}
+
+	syncRunsMu.Lock()
+	status.LastCheckTime = lastCheckTime
+	if currentRun != nil {
+		run := currentRun.run
+		status.CurrentRun = &run
+		status.Running = true
+	}
+	syncRunsMu.Unlock()
+
+	var err error
+	status.Leader, err = leader.GetLeader()
+	if err != nil {
+		return nil, err
+	}
+
+	status.LastRun, err = mongo.GetLastSyncRun("")
+	if err != nil {
+		return nil, err
+	}
+
+	status.LastSuccessRun, err = mongo.GetLastSyncRun(models.SyncStatusSuccess)
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/models"
+	"errors"
+
+	"github.com/golang/glog"
+)
+
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+func saveValidationReports(reports []*models.ValidationReport) {
+	for _, report := range reports {
+		err := mongo.UpsertValidationReport(report)
+		if err != nil {
+			glog.Warningf("UpsertValidationReport %s err:%s", report.Name, err.Error())
+		}
+	}
+}
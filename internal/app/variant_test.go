@@ -0,0 +1,73 @@
+package app
+
+import (
+	"app-store-server/internal/constants"
+	"app-store-server/internal/gitapp"
+	"app-store-server/internal/variant"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const templatedManifest = `olaresManifest.version: '0.8.0'
+olaresManifest.type: app
+metadata:
+  name: notes
+  title: Notes
+  icon: https://example.com/notes.png
+  version: 1.0.0
+  categories:
+  - Productivity
+{{- if eq .Values.GPU.Type "nvidia" }}
+  description: CUDA build
+{{- else }}
+  description: CPU build
+{{- end }}
+entrances:
+- name: web
+  port: 8080
+  title: Notes
+`
+
+func TestReadAppInfoRendersEveryVariant(t *testing.T) {
+	t.Setenv(variant.RenderVariantsEnv, `[
+		{"name":"cpu","values":{"GPU":{"Type":"none"}}},
+		{"name":"nvidia","values":{"GPU":{"Type":"nvidia"}}}
+	]`)
+	if err := variant.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv(variant.RenderVariantsEnv)
+		_ = variant.Init()
+	})
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "notes", constants.AppCfgFileName), []byte(templatedManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &gitapp.Source{Name: "local", Type: gitapp.SourceTypeLocal, Path: root, Prefix: "my-"}
+	info, report, err := readAppInfoWithReport(gitapp.AppDir{Source: source, Name: "notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// the primary context renders the app info, the others its variants
+	if info.Description != "CPU build" || info.Name != "my-notes" {
+		t.Errorf("app info = %s %q", info.Name, info.Description)
+	}
+	if len(info.Variants) != 1 {
+		t.Fatalf("variants = %v", info.Variants)
+	}
+	nvidia, ok := info.Variants["nvidia"]
+	if !ok || nvidia.Description != "CUDA build" || nvidia.Name != "my-notes" || nvidia.Source != "local" {
+		t.Errorf("nvidia variant = %+v", nvidia)
+	}
+}
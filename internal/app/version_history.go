@@ -0,0 +1,90 @@
+package app
+
+import (
+	"app-store-server/internal/gitapp"
+	"app-store-server/internal/variant"
+	"app-store-server/pkg/models"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v3"
+)
+
+// appManifestVersion holds the parts of a manifest the version history needs.
+type appManifestVersion struct {
+	Metadata struct {
+		Version string `yaml:"version"`
+	} `yaml:"metadata"`
+	Spec struct {
+		UpgradeDescription string `yaml:"upgradeDescription"`
+	} `yaml:"spec"`
+}
+
+// GetAppVersionHistory returns the versions of an app newest first, each with
+// the commit that introduced it, read from the local git history.
+func GetAppVersionHistory(name string) ([]*models.AppVersionHistory, error) {
+	appDir, exist := gitapp.FindAppDir(name)
+	if !exist {
+		return nil, fmt.Errorf("%s not exist", name)
+	}
+
+	commits, err := gitapp.GetAppCommits(appDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []*models.AppVersionHistory
+	// walk oldest first so each version is attributed to the commit introducing it
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		if c.Manifest == nil {
+			continue
+		}
+
+		version, upgradeDescription, err := parseManifestVersion(c.Manifest)
+		if err != nil {
+			glog.Warningf("parse manifest of %s at %s err:%s", name, c.Hash, err.Error())
+			continue
+		}
+		if version == "" {
+			continue
+		}
+		if len(history) > 0 && history[len(history)-1].Version == version {
+			continue
+		}
+
+		history = append(history, &models.AppVersionHistory{
+			Version:            version,
+			Commit:             c.Hash,
+			Author:             c.Author,
+			Date:               c.Time,
+			UpgradeDescription: upgradeDescription,
+		})
+	}
+
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	return history, nil
+}
+
+func parseManifestVersion(content []byte) (string, string, error) {
+	var manifest appManifestVersion
+	err := yaml.Unmarshal(content, &manifest)
+	if err == nil {
+		return manifest.Metadata.Version, manifest.Spec.UpgradeDescription, nil
+	}
+
+	if !strings.Contains(string(content), "{{") {
+		return "", "", err
+	}
+
+	info, err := renderAppConfigWithTemplate(string(content), variant.Primary())
+	if err != nil {
+		return "", "", err
+	}
+
+	return info.Version, info.UpgradeDescription, nil
+}
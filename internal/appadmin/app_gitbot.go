@@ -2,11 +2,81 @@ package appadmin
 
 import (
 	"app-store-server/internal/constants"
+	"app-store-server/pkg/models"
+	"encoding/json"
 	"fmt"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
 )
 
-func GetAppHistory(appName string) (string, error) {
+// gitBotAppHistory is the version history response of appstore-git-bot, the
+// versions of an app keyed by version and "latest".
+type gitBotAppHistory struct {
+	Data struct {
+		History map[string]struct {
+			Version            string `json:"version"`
+			LastCommitHash     string `json:"lastCommitHash"`
+			UpdateTime         int64  `json:"updateTime"`
+			UpgradeDescription string `json:"upgradeDescription"`
+		} `json:"history"`
+	} `json:"data"`
+}
+
+func getAppHistory(appName string) (string, error) {
 	url := fmt.Sprintf(constants.AppGitBotAppVersionHistoryURLTempl, getAppGitBotHost(), getAppGitBotPort(), appName)
 	bodyStr, err := sendHttpRequest("GET", url, nil)
 	return bodyStr, err
 }
+
+// GetAppVersionHistory returns the versions of an app from appstore-git-bot,
+// newest first, in the shape of the history read from the local git repository.
+// The git-bot does not tell the author of a version.
+func GetAppVersionHistory(appName string) ([]*models.AppVersionHistory, error) {
+	body, err := getAppHistory(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp gitBotAppHistory
+	err = json.Unmarshal([]byte(body), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parse git-bot history of %s: %w", appName, err)
+	}
+
+	seen := make(map[string]bool)
+	history := []*models.AppVersionHistory{}
+	for key, v := range resp.Data.History {
+		version := v.Version
+		if version == "" && key != "latest" {
+			version = key
+		}
+		if version == "" || seen[version] {
+			continue
+		}
+		seen[version] = true
+
+		history = append(history, &models.AppVersionHistory{
+			Version:            version,
+			Commit:             v.LastCommitHash,
+			Date:               v.UpdateTime,
+			UpgradeDescription: v.UpgradeDescription,
+		})
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		vi, erri := semver.NewVersion(history[i].Version)
+		vj, errj := semver.NewVersion(history[j].Version)
+		if erri != nil || errj != nil {
+			return history[i].Date > history[j].Date
+		}
+		return vi.GreaterThan(vj)
+	})
+
+	return history, nil
+}
+
+// GitBotConfigured reports whether the appstore-git-bot address is set.
+func GitBotConfigured() bool {
+	return getAppGitBotHost() != ""
+}
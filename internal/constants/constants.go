@@ -18,6 +18,7 @@ const (
 	APIServerListenAddress = ":8081"
 
 	AppGitLocalDir    = "/opt/app/app_git"
+	AppGitSourcesDir  = "/opt/app/app_git_sources"
 	AppGitZipLocalDir = "/opt/app/charts"
 	AppCfgFileName    = "OlaresManifest.yaml"
 	ReadmeFileName    = "README.md"
@@ -58,6 +59,8 @@ const (
 	RemoveFile  = ".remove"
 	SuspendFile = ".suspend"
 	NsfwFile    = ".nsfw"
+	// LabelsFile declares the labels of an app with their reason, time window and versions
+	LabelsFile = "labels.yaml"
 
 	RemoveLabel  = "remove"
 	SuspendLabel = "suspend"
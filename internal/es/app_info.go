@@ -3,6 +3,7 @@ package es
 import (
 	"app-store-server/internal/gitapp"
 	"app-store-server/pkg/models"
+	"app-store-server/pkg/redact"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -27,18 +28,37 @@ func existIndex() bool {
 	return false
 }
 
-func delIndex() {
-	suc, err := esClient.typedClient.Indices.Delete(indexName).IsSuccess(context.Background())
+// delIndices deletes the indices the alias pointed at before a rebuild.
+func delIndices(names []string) {
+	for _, name := range names {
+		suc, err := esClient.typedClient.Indices.Delete(name).IsSuccess(context.Background())
+		if err != nil {
+			glog.Warningf("index %s Delete err:%s", name, err.Error())
+			continue
+		}
+
+		if !suc {
+			glog.Warningf("index %s Delete failed", name)
+		}
+	}
+}
+
+// aliasedIndices returns the indices behind the indexName alias.
+func aliasedIndices() ([]string, error) {
+	resp, err := esClient.typedClient.Indices.GetAlias().Name(indexName).Do(context.Background())
 	if err != nil {
-		glog.Warningf("index %s Delete err:%s", indexName, err.Error())
+		return nil, err
 	}
 
-	if !suc {
-		glog.Warningf("index %s Delete failed", indexName)
+	names := make([]string, 0, len(resp))
+	for name := range resp {
+		names = append(names, name)
 	}
+
+	return names, nil
 }
 
-func createIndex() error {
+func createIndex(name string) error {
 	props := map[string]types.Property{
 		"history": types.ObjectProperty{
 			Properties: map[string]types.Property{
@@ -95,7 +115,11 @@ func createIndex() error {
 							Analyzer:       some.String("caseSensitive"),
 							SearchAnalyzer: some.String("caseSensitiveSearch"),
 						},
-						"categories":     types.TextProperty{},
+						"categories": types.TextProperty{
+							Fields: map[string]types.Property{
+								"keyword": types.KeywordProperty{},
+							},
+						},
 						"lastCommitHash": types.KeywordProperty{},
 						"createTime":     types.DateProperty{},
 						"updateTime":     types.DateProperty{},
@@ -104,16 +128,26 @@ func createIndex() error {
 			},
 		},
 	}
-	err := esClient.CreateIndexWithMapping(indexName, props)
+	err := esClient.CreateIndexWithMapping(name, props)
 	if err != nil {
-		glog.Warningf("createIndex err:%s", err.Error())
+		glog.Warningf("createIndex %s err:%s", name, err.Error())
 	}
 
 	return err
 }
 
+// UpsertAppInfoToDb indexes the app, without its sensitive fields when
+// redaction is enabled as the search results are served to any client.
 func UpsertAppInfoToDb(appInfo *models.ApplicationInfoFullData) error {
-	resp, err := esClient.typedClient.Index(indexName).Id(appInfo.Id).Request(appInfo).Do(context.TODO())
+	return upsertAppInfo(indexName, appInfo)
+}
+
+func upsertAppInfo(index string, appInfo *models.ApplicationInfoFullData) error {
+	if redact.Enabled() {
+		appInfo = redact.Redact(appInfo)
+	}
+
+	resp, err := esClient.typedClient.Index(index).Id(appInfo.Id).Request(appInfo).Do(context.TODO())
 	if err != nil {
 		glog.Warningf("resp:%+v, err:%s", resp, err.Error())
 		return err
@@ -303,7 +337,9 @@ func getWildcardName(word string) string {
 	return strings.ToLower(word)
 }
 
-func SearchByNameWildcard(from, size int, name string) (infos []*models.ApplicationInfoFullData, count int64, err error) {
+// SearchByNameWildcard searches the apps by name and texts, leaving out the
+// apps of hiddenCategories, matched exactly regardless of case.
+func SearchByNameWildcard(from, size int, name string, hiddenCategories []string) (infos []*models.ApplicationInfoFullData, count int64, err error) {
 	var resp *search.Response
 	var lastCommitHash string
 	lastCommitHash, err = gitapp.GetLastHash()
@@ -312,6 +348,15 @@ func SearchByNameWildcard(from, size int, name string) (infos []*models.Applicat
 		return
 	}
 
+	var hidden []types.Query
+	for _, category := range hiddenCategories {
+		hidden = append(hidden, types.Query{
+			Term: map[string]types.TermQuery{
+				"history.latest.categories.keyword": {Value: category, CaseInsensitive: some.Bool(true)},
+			},
+		})
+	}
+
 	wildcardName := getWildcardName(name)
 	resp, err = esClient.typedClient.Search().
 		Index(indexName).
@@ -321,6 +366,7 @@ func SearchByNameWildcard(from, size int, name string) (infos []*models.Applicat
 				From: some.Int(from),
 				Query: &types.Query{
 					Bool: &types.BoolQuery{
+						MustNot: hidden,
 						Filter: []types.Query{
 							{
 								Term: map[string]types.TermQuery{
@@ -419,3 +465,7 @@ func SearchByNameWildcard(from, size int, name string) (infos []*models.Applicat
 
 	return
 }
+
+func DeleteAppInfoFromDb(id string) error {
+	return esClient.DelOneDoc(indexName, id)
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -16,6 +17,7 @@ import (
 
 	es8 "github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/update"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/some"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
 	"github.com/golang/glog"
 )
@@ -28,6 +30,8 @@ var (
 	esClient *Client
 )
 
+// indexName is the alias searches and writes go through, each rebuild
+// creates a new index behind it
 const indexName = "app_info"
 
 func Init() error {
@@ -46,16 +50,92 @@ func initWithRetry() error {
 		return err
 	}
 
-	if existIndex() {
-		delIndex()
+	// the leader rebuilds the index, the other replicas must not drop it under it
+	if !existIndex() {
+		return initIndex()
 	}
-	createIndex()
+
+	return nil
+}
+
+func newIndexName() string {
+	return fmt.Sprintf("%s-%d", indexName, time.Now().UnixNano())
+}
+
+// initIndex creates an empty index behind the alias on a fresh ES.
+func initIndex() error {
+	name := newIndexName()
+	err := createIndex(name)
+	if err != nil {
+		return err
+	}
+
+	return switchAlias(name, nil, false)
+}
+
+// switchAlias points the alias at name instead of old in one step. legacy
+// drops the index that carried the alias name before aliases were used.
+func switchAlias(name string, old []string, legacy bool) error {
+	actions := []types.IndicesAction{
+		{Add: &types.AddAction{Index: some.String(name), Alias: some.String(indexName)}},
+	}
+	for _, o := range old {
+		actions = append(actions, types.IndicesAction{
+			Remove: &types.RemoveAction{Index: some.String(o), Alias: some.String(indexName)},
+		})
+	}
+	if legacy {
+		actions = append(actions, types.IndicesAction{
+			RemoveIndex: &types.RemoveIndexAction{Index: some.String(indexName)},
+		})
+	}
+
+	_, err := esClient.typedClient.Indices.UpdateAliases().Actions(actions...).Do(context.TODO())
+	if err != nil {
+		glog.Warningf("switch alias %s to %s err:%s", indexName, name, err.Error())
+	}
+
+	return err
+}
+
+// RebuildIndex fills a new index from mongo, so mapping changes apply, and
+// switches the alias to it once it is complete. The old index keeps serving
+// searches meanwhile. Nothing is switched when leading reports false by then.
+func RebuildIndex(leading func() bool) error {
+	old, err := aliasedIndices()
+	// an index named like the alias predates the alias
+	legacy := err != nil && existIndex()
+
+	name := newIndexName()
+	err = createIndex(name)
+	if err != nil {
+		return err
+	}
+
+	err = utils.RetryFunction(func() error {
+		return syncAppInfosFromMongoToEs(name)
+	}, 3, time.Second)
+	if err == nil && !leading() {
+		err = errors.New("lost the leadership while rebuilding the index")
+	}
+	if err == nil {
+		err = switchAlias(name, old, legacy)
+	}
+	if err != nil {
+		delIndices([]string{name})
+		return err
+	}
+
+	delIndices(old)
+	glog.Infof("index %s rebuilt as %s", indexName, name)
 
 	return nil
 }
 
 func SyncInfoFromMongo() error {
-	err := utils.RetryFunction(syncAppInfosFromMongoToEs, 3, time.Second)
+	err := utils.RetryFunction(func() error {
+		return syncAppInfosFromMongoToEs(indexName)
+	}, 3, time.Second)
 	if err != nil {
 		glog.Warningf("syncAppInfosFromMongoToEs err:%s", err.Error())
 		return err
@@ -65,18 +145,19 @@ func SyncInfoFromMongo() error {
 	return nil
 }
 
-func syncAppInfosFromMongoToEs() error {
+func syncAppInfosFromMongoToEs(index string) error {
 	pageSize := int64(1000)
 	for offset := int64(0); ; {
-		infos, _, err := mongo.GetAppLists(offset, pageSize, "", "")
+		infos, _, err := mongo.GetAllAppLists(offset, pageSize)
 		if err != nil {
 			glog.Warningf("GetAppLists err:%s", err.Error())
-			break
+			// a partial index must not replace a complete one
+			return err
 		}
 		glog.Infof("success get %d docs from mongodb", len(infos))
 
 		for _, info := range infos {
-			err = UpsertAppInfoToDb(info)
+			err = upsertAppInfo(index, info)
 			if err != nil {
 				glog.Warningf("UpsertAppInfoToDb err:%s", err.Error())
 				continue
@@ -0,0 +1,169 @@
+package gitapp
+
+import (
+	"app-store-server/internal/constants"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var ErrNoHistory = errors.New("source has no git history")
+
+// AppCommit is a commit changing an app directory, with the manifest the app
+// had at that commit.
+type AppCommit struct {
+	Hash   string
+	Author string
+	Email  string
+	Time   int64
+	// Manifest is nil when the directory had no manifest at the commit
+	Manifest []byte
+}
+
+type appCommitsCache struct {
+	hash    plumbing.Hash
+	commits []AppCommit
+}
+
+var (
+	appCommitsMu sync.Mutex
+	// appCommitsCaches caches the commits of an app directory for the HEAD they were walked from
+	appCommitsCaches = make(map[string]*appCommitsCache)
+)
+
+// GetAppCommits returns the commits changing the directory of an app, newest
+// first. Merge commits are skipped like for the app times.
+func GetAppCommits(d AppDir) ([]AppCommit, error) {
+	if d.Source.isLocal() {
+		return nil, ErrNoHistory
+	}
+
+	r, err := git.PlainOpen(d.Source.Dir())
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	key := d.Path()
+	appCommitsMu.Lock()
+	c, ok := appCommitsCaches[key]
+	appCommitsMu.Unlock()
+	if ok && c.hash == head.Hash() {
+		return c.commits, nil
+	}
+
+	commits, err := walkAppCommits(r, head.Hash(), d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	appCommitsMu.Lock()
+	appCommitsCaches[key] = &appCommitsCache{hash: head.Hash(), commits: commits}
+	appCommitsMu.Unlock()
+
+	return commits, nil
+}
+
+func walkAppCommits(r *git.Repository, head plumbing.Hash, name string) ([]AppCommit, error) {
+	iter, err := r.Log(&git.LogOptions{
+		From:  head,
+		Order: git.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var commits []AppCommit
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.NumParents() > 1 {
+			return nil
+		}
+
+		hash, err := appDirHash(c, name)
+		if err != nil {
+			return err
+		}
+		if hash.IsZero() {
+			return nil
+		}
+
+		if c.NumParents() > 0 {
+			parent, err := c.Parent(0)
+			if err != nil {
+				return err
+			}
+			parentHash, err := appDirHash(parent, name)
+			if err != nil {
+				return err
+			}
+			if parentHash == hash {
+				return nil
+			}
+		}
+
+		manifest, err := readCommitFile(c, path.Join(name, constants.AppCfgFileName))
+		if err != nil {
+			return err
+		}
+
+		commits = append(commits, AppCommit{
+			Hash:     c.Hash.String(),
+			Author:   c.Author.Name,
+			Email:    c.Author.Email,
+			Time:     c.Author.When.Unix(),
+			Manifest: manifest,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// appDirHash returns the tree hash of the app directory at a commit, zero when it does not exist.
+func appDirHash(c *object.Commit, name string) (plumbing.Hash, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	entry, err := tree.FindEntry(name)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return entry.Hash, nil
+}
+
+func readCommitFile(c *object.Commit, name string) ([]byte, error) {
+	f, err := c.File(name)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s at %s: %w", name, c.Hash, err)
+	}
+
+	content, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(content), nil
+}
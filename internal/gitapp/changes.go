@@ -0,0 +1,148 @@
+package gitapp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// AppDirChanges lists the app directories touched between two sets of source commits.
+type AppDirChanges struct {
+	// Updated holds directories added or modified, they exist in the new commits.
+	Updated []AppDir
+	// Removed holds the names of the apps whose directory no longer exists.
+	Removed []string
+}
+
+// ChangedAppDirs compares the top level trees of the recorded commits of every
+// source with their current HEAD. An app directory changed iff the hash of its
+// subtree changed, so no recursive diff is needed.
+func ChangedAppDirs(oldHeads map[string]string) (*AppDirChanges, error) {
+	changes := &AppDirChanges{}
+	changedNames := make(map[string]bool)
+
+	for _, s := range sources {
+		oldHash, ok := oldHeads[s.Name]
+		if !ok || oldHash == "" {
+			return nil, fmt.Errorf("no recorded commit for source %s", s.Name)
+		}
+
+		if s.isLocal() {
+			// a local source has no history to diff against
+			head, err := s.head()
+			if err != nil {
+				return nil, err
+			}
+			if head != oldHash {
+				return nil, fmt.Errorf("local source %s changed", s.Name)
+			}
+			continue
+		}
+
+		r, err := git.PlainOpen(s.Dir())
+		if err != nil {
+			return nil, err
+		}
+
+		head, err := r.Head()
+		if err != nil {
+			return nil, err
+		}
+
+		changed, removed, err := changedAppDirsBetween(r, plumbing.NewHash(oldHash), head.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", s.Name, err)
+		}
+
+		for _, name := range changed {
+			changedNames[s.Prefix+name] = true
+		}
+		for _, name := range removed {
+			changedNames[s.Prefix+name] = true
+			changes.Removed = append(changes.Removed, s.Prefix+name)
+		}
+	}
+	sort.Strings(changes.Removed)
+
+	// reprocess a changed app in every source providing it, so that name
+	// collisions between sources are resolved again
+	appDirs, err := ListAppDirs()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range appDirs {
+		if changedNames[d.AppName()] {
+			changes.Updated = append(changes.Updated, d)
+		}
+	}
+
+	return changes, nil
+}
+
+// changedAppDirsBetween returns the app directories of a repository that are
+// new or modified at to, and those of from that no longer exist at to.
+func changedAppDirsBetween(r *git.Repository, from, to plumbing.Hash) (changed, removed []string, err error) {
+	fromDirs, err := appDirTreesAt(r, from)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read tree of %s: %w", from, err)
+	}
+
+	toDirs, err := appDirTreesAt(r, to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read tree of %s: %w", to, err)
+	}
+
+	for name := range fromDirs {
+		if _, ok := toDirs[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	return diffAppDirTrees(fromDirs, toDirs), removed, nil
+}
+
+func appDirTreesAt(r *git.Repository, hash plumbing.Hash) (map[string]plumbing.Hash, error) {
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return appDirTrees(commit)
+}
+
+// appDirTrees maps every top level app directory of a commit to its tree hash.
+func appDirTrees(commit *object.Commit) (map[string]plumbing.Hash, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]plumbing.Hash)
+	for _, entry := range tree.Entries {
+		if entry.Mode != filemode.Dir || strings.HasPrefix(entry.Name, ".") {
+			continue
+		}
+		dirs[entry.Name] = entry.Hash
+	}
+
+	return dirs, nil
+}
+
+// diffAppDirTrees returns the directories of to that are new or differ from from.
+func diffAppDirTrees(from, to map[string]plumbing.Hash) []string {
+	var changed []string
+	for name, hash := range to {
+		if old, ok := from[name]; !ok || old != hash {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed
+}
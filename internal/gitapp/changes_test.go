@@ -0,0 +1,63 @@
+package gitapp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestChangedAppDirsBetween(t *testing.T) {
+	r := newTestRepo(t)
+
+	r.write("notes/Chart.yaml", "v1")
+	r.write("files/Chart.yaml", "v1")
+	r.write("photos/Chart.yaml", "v1")
+	r.write("README.md", "catalog")
+	from := r.commit(time.Unix(1000, 0))
+
+	r.write("notes/templates/deployment.yaml", "kind: Deployment")
+	r.remove("files")
+	r.write("music/Chart.yaml", "v1")
+	r.write("README.md", "the catalog")
+	r.write(".github/workflow.yaml", "ci")
+	r.commit(time.Unix(2000, 0))
+
+	// photos changes and changes back, its tree is the same as at from
+	r.write("photos/Chart.yaml", "v2")
+	r.commit(time.Unix(3000, 0))
+	r.write("photos/Chart.yaml", "v1")
+	to := r.commit(time.Unix(4000, 0))
+
+	changed, removed, err := changedAppDirsBetween(r.repo, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"music", "notes"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"files"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+
+	changed, removed, err = changedAppDirsBetween(r.repo, to, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != nil || removed != nil {
+		t.Errorf("changes of a commit with itself = %v, %v", changed, removed)
+	}
+}
+
+func TestChangedAppDirsBetweenUnknownCommit(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("notes/Chart.yaml", "v1")
+	head := r.commit(time.Unix(1000, 0))
+
+	missing := plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")
+	if _, _, err := changedAppDirsBetween(r.repo, missing, head); err == nil {
+		t.Error("no error for a commit missing from the repository")
+	}
+}
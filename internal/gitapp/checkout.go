@@ -0,0 +1,142 @@
+package gitapp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/golang/glog"
+)
+
+// openCheckout returns nil when the checkout of s on disk can be reused: it is
+// a clone of the configured url with the configured branch and its objects are
+// intact. Uncommitted changes to the worktree are discarded.
+func openCheckout(s *Source) error {
+	r, err := git.PlainOpen(s.Dir())
+	if err != nil {
+		return err
+	}
+
+	return reuseCheckout(s, r)
+}
+
+func reuseCheckout(s *Source, r *git.Repository) error {
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return err
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 || normalizeRepoURL(urls[0]) != normalizeRepoURL(s.URL) {
+		origin := ""
+		if len(urls) > 0 {
+			origin = redactURL(urls[0])
+		}
+		return fmt.Errorf("origin is %q, configured %q", origin, redactURL(s.URL))
+	}
+	if urls[0] != s.URL {
+		// the same repository spelled differently, e.g. with .git or over ssh, fetch from the configured url
+		cfg, err := r.Config()
+		if err != nil {
+			return err
+		}
+		cfg.Remotes["origin"].URLs = []string{s.URL}
+		err = r.SetConfig(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = r.Reference(plumbing.NewBranchReferenceName(s.Branch), true)
+	if err != nil {
+		return fmt.Errorf("branch %s: %w", s.Branch, err)
+	}
+
+	err = checkIntegrity(r)
+	if err != nil {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	status, err := w.Status()
+	if err != nil {
+		return err
+	}
+	if !status.IsClean() {
+		glog.Warningf("checkout of source %s has local changes, reset", s.Name)
+		err = w.Reset(&git.ResetOptions{Mode: git.HardReset})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkIntegrity makes sure the HEAD commit, its trees and the blobs they
+// reference are all in the object store.
+func checkIntegrity(r *git.Repository) error {
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("HEAD commit %s: %w", head.Hash(), err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("tree of %s: %w", head.Hash(), err)
+	}
+
+	return tree.Files().ForEach(func(f *object.File) error {
+		_, err := r.Storer.EncodedObject(plumbing.BlobObject, f.Hash)
+		if err != nil {
+			return fmt.Errorf("blob %s of %s: %w", f.Hash, f.Name, err)
+		}
+		return nil
+	})
+}
+
+// prepareCheckout reuses the checkout of s when it is intact and brings it up
+// to date, or clones s again. A failed update of a reused checkout is only
+// logged, the catalog is served from it as it is.
+func prepareCheckout(s *Source) error {
+	err := openCheckout(s)
+	if err != nil {
+		glog.Warningf("checkout of source %s not reusable, clone again: %s", s.Name, err.Error())
+		return cloneSource(s)
+	}
+
+	glog.Infof("reuse checkout of source %s in %s", s.Name, s.Dir())
+	err = pullSource(s)
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		glog.Warningf("update of source %s failed, serving the existing checkout: %s", s.Name, err.Error())
+	}
+
+	return nil
+}
+
+// cloneSource clones s from scratch and checks out its pin.
+func cloneSource(s *Source) error {
+	err := cloneCode(s)
+	if err != nil {
+		return err
+	}
+
+	if pin := pinnedRef(s); pin != "" {
+		err = checkoutPin(s, pin)
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package gitapp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// cloneTestRepo clones upstream into a temporary directory like a source checkout.
+func cloneTestRepo(t *testing.T, upstream *testRepo) *git.Repository {
+	t.Helper()
+
+	r, err := git.PlainClone(filepath.Join(t.TempDir(), "checkout"), false, &git.CloneOptions{URL: upstream.dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func worktreeDir(t *testing.T, r *git.Repository) string {
+	t.Helper()
+
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w.Filesystem.Root()
+}
+
+func TestReuseCheckout(t *testing.T) {
+	upstream := newTestRepo(t)
+	upstream.write("notes/Chart.yaml", "v1")
+	upstream.commit(time.Unix(1000, 0))
+
+	tests := []struct {
+		name   string
+		source *Source
+		valid  bool
+	}{
+		{name: "configured url and branch", source: &Source{Name: "s", URL: upstream.dir, Branch: "master"}, valid: true},
+		{name: "same repository spelled differently", source: &Source{Name: "s", URL: upstream.dir + ".git", Branch: "master"}, valid: true},
+		{name: "other repository", source: &Source{Name: "s", URL: "https://github.com/someone/apps.git", Branch: "master"}},
+		{name: "branch missing from the checkout", source: &Source{Name: "s", URL: upstream.dir, Branch: "release"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := cloneTestRepo(t, upstream)
+
+			err := reuseCheckout(tt.source, r)
+			if (err == nil) != tt.valid {
+				t.Fatalf("reuseCheckout = %v, want valid %v", err, tt.valid)
+			}
+			if !tt.valid {
+				return
+			}
+
+			remote, err := r.Remote("origin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if urls := remote.Config().URLs; len(urls) != 1 || urls[0] != tt.source.URL {
+				t.Errorf("origin = %v, want the configured url %s", urls, tt.source.URL)
+			}
+		})
+	}
+}
+
+func TestReuseCheckoutResetsLocalChanges(t *testing.T) {
+	upstream := newTestRepo(t)
+	upstream.write("notes/Chart.yaml", "v1")
+	upstream.commit(time.Unix(1000, 0))
+
+	r := cloneTestRepo(t, upstream)
+	chart := filepath.Join(worktreeDir(t, r), "notes", "Chart.yaml")
+	if err := os.WriteFile(chart, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reuseCheckout(&Source{Name: "s", URL: upstream.dir, Branch: "master"}, r); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(chart); err != nil || string(data) != "v1" {
+		t.Errorf("Chart.yaml = %q, %v, want the committed content", data, err)
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("notes/Chart.yaml", "v1")
+	r.write("files/Chart.yaml", "v1")
+	head := r.commit(time.Unix(1000, 0))
+
+	if err := checkIntegrity(r.repo); err != nil {
+		t.Fatalf("intact repository: %v", err)
+	}
+
+	commit, err := r.repo.CommitObject(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := commit.File("notes/Chart.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the loose object of the blob
+	hex := f.Hash.String()
+	if err := os.Remove(filepath.Join(r.dir, ".git", "objects", hex[:2], hex[2:])); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := git.PlainOpen(r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkIntegrity(reopened); err == nil {
+		t.Error("no error for a missing blob")
+	}
+}
@@ -1,15 +1,14 @@
 package gitapp
 
 import (
-	"app-store-server/internal/constants"
+	"app-store-server/internal/leader"
 	"app-store-server/internal/mongo"
 	"app-store-server/pkg/utils"
 
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -45,14 +44,58 @@ func getGitBranch() string {
 }
 
 func Init() error {
-	return utils.RetryFunction(cloneCode, 3, time.Second)
+	err := loadSources()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sources {
+		src := s
+		if src.isLocal() {
+			err = syncLocalSource(src)
+			if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+				glog.Warningf("prepare local source %s failed: %s", src.Name, err.Error())
+				return err
+			}
+			continue
+		}
+
+		err = utils.RetryFunction(func() error {
+			return prepareCheckout(src)
+		}, 3, time.Second)
+		if err != nil {
+			glog.Warningf("clone source %s failed: %s", src.Name, err.Error())
+			return err
+		}
+	}
+
+	if !leader.IsLeader() {
+		err = Follow()
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			glog.Warningf("follow the leader checkouts failed: %s", err.Error())
+		}
+		return nil
+	}
+
+	accepted := getAcceptedHeads()
+	for _, s := range sources {
+		err = acceptSourceHead(s, accepted[s.Name])
+		// a rejected source keeps its accepted commit or serves nothing, the
+		// rejection shows in the sync status
+		if err != nil && !errors.Is(err, ErrUntrustedCommit) {
+			return err
+		}
+	}
+
+	_, err = GetLastCommitHashAndUpdate()
+	return err
 }
 
-func cloneCode() error {
+func cloneCode(s *Source) error {
 	//clear local git dir
-	err := os.RemoveAll(constants.AppGitLocalDir)
+	err := os.RemoveAll(s.Dir())
 	if err != nil {
-		glog.Warningf("os.RemoveAll %s %s", constants.AppGitLocalDir, err.Error())
+		glog.Warningf("os.RemoveAll %s %s", s.Dir(), err.Error())
 		return err
 	}
 
@@ -63,15 +106,21 @@ func cloneCode() error {
 	// 	return err
 	// }
 
-	return gitClone(getGitAddr(), getGitBranch(), constants.AppGitLocalDir)
+	return gitClone(s)
 }
 
-func gitClone(url, branch, directory string) error {
+func gitClone(s *Source) error {
+	auth, err := s.authMethod()
+	if err != nil {
+		return err
+	}
+
 	//clone
-	r, err := git.PlainClone(directory, false, &git.CloneOptions{
-		URL:           url,
+	r, err := git.PlainClone(s.Dir(), false, &git.CloneOptions{
+		URL:           s.URL,
+		Auth:          auth,
 		Progress:      os.Stdout,
-		ReferenceName: plumbing.ReferenceName(branch),
+		ReferenceName: plumbing.ReferenceName(s.Branch),
 	})
 	if err != nil {
 		glog.Warningf("git clone failed: %s", err.Error())
@@ -89,19 +138,134 @@ func gitClone(url, branch, directory string) error {
 		glog.Warningf("failed to get commit: %s", err.Error())
 		return err
 	}
-
-	updateLastHash(commit.Hash.String())
+	glog.Infof("cloned %s@%s into %s at %s", redactURL(s.URL), s.Branch, s.Dir(), commit.Hash.String())
 
 	return nil
 }
 
+// Pull pulls every source, or moves it to its pin. It returns
+// git.NoErrAlreadyUpToDate when no HEAD moved, and an error only when no
+// source could be updated at all.
 func Pull() error {
-	return gitPull(constants.AppGitLocalDir)
+	checkoutMu.Lock()
+	defer checkoutMu.Unlock()
+
+	accepted := getAcceptedHeads()
+
+	var lastErr error
+	updated, failed := 0, 0
+	for _, s := range sources {
+		before, _ := s.head()
+
+		err := pullSource(s)
+		if err == nil {
+			err = acceptSourceHead(s, accepted[s.Name])
+		}
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			failed++
+			lastErr = err
+			glog.Warningf("pull source %s failed: %s", s.Name, err.Error())
+			continue
+		}
+
+		after, _ := s.head()
+		if after != before {
+			updated++
+		}
+	}
+
+	if failed == len(sources) {
+		return lastErr
+	}
+	if updated == 0 {
+		return git.NoErrAlreadyUpToDate
+	}
+
+	return nil
+}
+
+// Follow moves the checkouts of a replica that is not the leader to the source
+// commits the leader recorded, so it serves the catalog the leader built. Like
+// Pull it returns git.NoErrAlreadyUpToDate when no checkout moved.
+func Follow() error {
+	checkoutMu.Lock()
+	defer checkoutMu.Unlock()
+
+	heads := getAcceptedHeads()
+
+	var lastErr error
+	updated, failed := 0, 0
+	for _, s := range sources {
+		before, _ := s.head()
+
+		err := followSource(s, heads[s.Name])
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			failed++
+			lastErr = err
+			glog.Warningf("follow source %s failed: %s", s.Name, err.Error())
+			continue
+		}
+
+		after, _ := s.head()
+		if after != before {
+			updated++
+		}
+	}
+
+	if failed == len(sources) {
+		return lastErr
+	}
+	if updated == 0 {
+		return git.NoErrAlreadyUpToDate
+	}
+
+	return nil
+}
+
+func followSource(s *Source, hash string) error {
+	if s.isLocal() {
+		return syncLocalSource(s)
+	}
+
+	// nothing recorded for the source yet, track it as the leader would,
+	// unless its commits must be verified first
+	if hash == "" {
+		if getVerifyMode() != "" {
+			setWithheld(s, true)
+			return git.NoErrAlreadyUpToDate
+		}
+		return pullSource(s)
+	}
+
+	setWithheld(s, false)
+	return checkoutPin(s, hash)
 }
 
-// gitPull attempts to pull using V3 first, falls back to V2 and then V1 if previous attempts fail
-func gitPull(directory string) error {
-	err := gitPullV3(directory)
+func pullSource(s *Source) error {
+	if s.isLocal() {
+		return syncLocalSource(s)
+	}
+
+	if pin := pinnedRef(s); pin != "" {
+		return checkoutPin(s, pin)
+	}
+
+	err := checkoutBranch(s)
+	if err != nil {
+		return err
+	}
+
+	return gitPull(s)
+}
+
+// gitPull attempts to pull using V3 first, falls back to V2 and then V1 if previous attempts fail.
+// Sources with credentials only use V3, the git command line would not use them.
+func gitPull(s *Source) error {
+	directory := s.Dir()
+	err := gitPullV3(s)
+	if err != nil && s.hasAuth() {
+		return err
+	}
 	if err != nil {
 		glog.Warningf("gitPullV3 failed, fallback to V2: %v", err)
 		err = gitPullV2(directory)
@@ -115,23 +279,13 @@ func gitPull(directory string) error {
 
 // gitPullV1 is the original implementation
 func gitPullV1(directory string) error {
-	curDir, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-	defer os.Chdir(curDir)
-
 	dir, err := filepath.Abs(directory)
 	if err != nil {
 		return err
 	}
 
-	err = os.Chdir(dir)
-	if err != nil {
-		return err
-	}
-
 	cmd := exec.Command("git", "pull")
+	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return err
@@ -157,24 +311,9 @@ func gitPullV2(directory string) error {
 		return fmt.Errorf("directory is not accessible: %w", err)
 	}
 
-	curDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %w", err)
-	}
-
-	// Ensure we restore the original working directory when function returns
-	defer func() {
-		if err := os.Chdir(curDir); err != nil {
-			glog.Warningf("failed to restore working directory: %v", err)
-		}
-	}()
-
-	// Change to target directory
-	if err := os.Chdir(dir); err != nil {
-		return fmt.Errorf("failed to change working directory: %w", err)
-	}
-
+	// Run git in the target directory, os.Chdir would change it for the whole process
 	cmd := exec.Command("git", "pull")
+	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("git pull failed: %w", err)
@@ -188,9 +327,14 @@ func gitPullV2(directory string) error {
 }
 
 // gitPullV3 implements pull using go-git library
-func gitPullV3(directory string) error {
+func gitPullV3(s *Source) error {
+	auth, err := s.authMethod()
+	if err != nil {
+		return err
+	}
+
 	// Open existing repository
-	r, err := git.PlainOpen(directory)
+	r, err := git.PlainOpen(s.Dir())
 	if err != nil {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
@@ -204,6 +348,7 @@ func gitPullV3(directory string) error {
 	// Configure pull options
 	opts := &git.PullOptions{
 		RemoteName: "origin",
+		Auth:       auth,
 		Progress:   os.Stdout,
 		Force:      true,
 	}
@@ -218,173 +363,112 @@ func gitPullV3(directory string) error {
 }
 
 func AppDirExist(name string) bool {
-	filePath := path.Join(constants.AppGitLocalDir, name)
-	exist, err := utils.PathExists(filePath)
-	if err != nil {
-		glog.Warningf("utils.PathExists %s %s", filePath, err.Error())
-	}
-
+	_, exist := FindAppDir(name)
 	return exist
 }
 
-func ReadMe(name string) ([]byte, error) {
-	exist := AppDirExist(name)
-	if !exist {
-		return nil, fmt.Errorf("%s not exist", name)
-	}
-
-	readFileName := path.Join(constants.AppGitLocalDir, name, constants.ReadmeFileName)
-
-	f, err := os.Open(readFileName)
-	if err != nil {
-		glog.Warningf("%s", err.Error())
-		return nil, err
-	}
-
-	content, err := io.ReadAll(f)
-	if err != nil {
-		glog.Warningf("%s", err.Error())
-		return nil, err
-	}
-
-	return content, nil
-}
-
-func updateLastHash(hash string) error {
-	return mongo.SetLastCommitHashToDB(hash)
-}
-
 func GetLastHash() (hash string, err error) {
 	hash, err = mongo.GetLastCommitHashFromDB()
 	if err == nil && hash != "" {
 		return hash, nil
 	}
 
-	return getGitLastCommitHash(constants.AppGitLocalDir)
-}
-
-func GetLastCommitHashAndUpdate() error {
-	hash, err := getGitLastCommitHash(constants.AppGitLocalDir)
-	if err != nil {
-		glog.Warningf("getGitLastCommitHash err:%s", err.Error())
-		return err
-	}
-	glog.Warningf("git hash:%s", hash)
-
-	err = updateLastHash(hash)
-	if err != nil {
-		glog.Warningf("updateLastHash err:%s", err.Error())
-		return err
-	}
-
-	return nil
-}
-
-func getGitLastCommitHash(directory string) (string, error) {
-	r, err := git.PlainOpen(directory)
-	if err != nil {
-		glog.Warningf("err:%s", err.Error())
-		return "", err
-	}
-
-	ref, err := r.Head()
-	if err != nil {
-		glog.Warningf("err:%s", err.Error())
-		return "", err
-	}
-	commit, err := r.CommitObject(ref.Hash())
+	heads, err := getSourceHeads()
 	if err != nil {
-		glog.Warningf("err:%s", err.Error())
 		return "", err
 	}
-	// Print the latest commit that was just pulled
-	glog.Infof("commit:%#v", commit)
 
-	return commit.Hash.String(), nil
+	return catalogHash(heads), nil
 }
 
-func GetCreateTimeSecond(dirPath, subDirPath string) (int64, error) {
-	timeStr, err := getCreateTime(dirPath, subDirPath)
+// GetLastSourceHashes returns the HEAD commit of every source as recorded by the last update.
+func GetLastSourceHashes() (map[string]string, error) {
+	heads, err := mongo.GetSourceCommitHashesFromDB()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	t, err := time.Parse(constants.TimeFormatStr, timeStr)
-	if err != nil {
-		return 0, err
+	// records written before sources existed only hold the default source hash
+	if len(heads) == 0 {
+		hash, err := mongo.GetLastCommitHashFromDB()
+		if err != nil {
+			return nil, err
+		}
+		heads = map[string]string{DefaultSourceName: hash}
 	}
 
-	return t.Unix(), nil
+	return heads, nil
 }
 
-func GetLastUpdateTimeSecond(dirPath, subDirPath string) (int64, error) {
-	timeStr, err := getLastUpdateTime(dirPath, subDirPath)
-	if err != nil {
-		return 0, err
-	}
-
-	t, err := time.Parse(constants.TimeFormatStr, timeStr)
+// GetLastCommitHashAndUpdate records the current HEAD of every source and the
+// combined catalog hash, which it returns.
+func GetLastCommitHashAndUpdate() (string, error) {
+	heads, err := getSourceHeads()
 	if err != nil {
-		return 0, err
+		return "", err
 	}
 
-	return t.Unix(), nil
-}
+	hash := catalogHash(heads)
+	glog.Warningf("git hash:%s, sources:%v", hash, heads)
 
-func getLastUpdateTime(dirPath, subDirPath string) (string, error) {
-	curDir, err := os.Getwd()
+	err = mongo.SetLastCommitHashesToDB(hash, heads)
 	if err != nil {
+		glog.Warningf("SetLastCommitHashesToDB err:%s", err.Error())
 		return "", err
 	}
-	defer os.Chdir(curDir)
 
-	dir, err := filepath.Abs(dirPath)
-	if err != nil {
-		return "", err
-	}
+	return hash, nil
+}
 
-	err = os.Chdir(dir)
+// getAcceptedHeads returns the source commits the catalog was last built
+// from, they passed verification when it is enabled.
+func getAcceptedHeads() map[string]string {
+	heads, err := GetLastSourceHashes()
 	if err != nil {
-		return "", err
+		return map[string]string{}
 	}
 
-	cmd := exec.Command("git", "log", "-1", `--pretty=format:%ad`, `--`, subDirPath)
+	return heads
+}
 
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", err
+func getSourceHeads() (map[string]string, error) {
+	heads := make(map[string]string)
+	for _, s := range sources {
+		// the HEAD of a withheld source is not accepted
+		if isWithheld(s) {
+			heads[s.Name] = ""
+			continue
+		}
+		hash, err := s.head()
+		if err != nil {
+			glog.Warningf("head of source %s err:%s", s.Name, err.Error())
+			return nil, err
+		}
+		heads[s.Name] = hash
 	}
 
-	return string(out), nil
+	return heads, nil
 }
 
-func getCreateTime(dirPath, subDirPath string) (string, error) {
-	curDir, err := os.Getwd()
+func getGitLastCommitHash(directory string) (string, error) {
+	r, err := git.PlainOpen(directory)
 	if err != nil {
+		glog.Warningf("err:%s", err.Error())
 		return "", err
 	}
-	defer os.Chdir(curDir)
 
-	dir, err := filepath.Abs(dirPath)
-	if err != nil {
-		return "", err
-	}
-	err = os.Chdir(dir)
+	ref, err := r.Head()
 	if err != nil {
+		glog.Warningf("err:%s", err.Error())
 		return "", err
 	}
-
-	cmd := exec.Command("git", "log", `--pretty=format:%ad`, `--`, subDirPath)
-	out, err := cmd.CombinedOutput()
+	commit, err := r.CommitObject(ref.Hash())
 	if err != nil {
+		glog.Warningf("err:%s", err.Error())
 		return "", err
 	}
+	// Print the latest commit that was just pulled
+	glog.Infof("commit:%#v", commit)
 
-	outStr := string(out)
-	outStrSlice := strings.Split(outStr, "\n")
-	if len(outStrSlice) <= 0 {
-		return "", fmt.Errorf("%s not contain \\n", outStr)
-	}
-
-	return outStrSlice[len(outStrSlice)-1], nil
+	return commit.Hash.String(), nil
 }
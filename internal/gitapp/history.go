@@ -0,0 +1,166 @@
+package gitapp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/golang/glog"
+)
+
+// appDirTimes holds the author time of the first and the last commit touching an app directory.
+type appDirTimes struct {
+	createTime int64
+	updateTime int64
+}
+
+type appDirTimesCache struct {
+	mu    sync.Mutex
+	hash  plumbing.Hash
+	times map[string]appDirTimes
+}
+
+var (
+	dirTimesCachesMu sync.Mutex
+	// dirTimesCaches caches the app dir times of a repository directory for its current HEAD
+	dirTimesCaches = make(map[string]*appDirTimesCache)
+)
+
+func getAppDirTimesCache(directory string) *appDirTimesCache {
+	dirTimesCachesMu.Lock()
+	defer dirTimesCachesMu.Unlock()
+
+	c, ok := dirTimesCaches[directory]
+	if !ok {
+		c = &appDirTimesCache{}
+		dirTimesCaches[directory] = c
+	}
+
+	return c
+}
+
+// getAppDirTimes returns the create and update times of every app directory at
+// HEAD. The history is walked once per HEAD commit and shared by all callers.
+func getAppDirTimes(directory string) (map[string]appDirTimes, error) {
+	r, err := git.PlainOpen(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	c := getAppDirTimesCache(directory)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.times != nil && c.hash == head.Hash() {
+		return c.times, nil
+	}
+
+	times, err := walkAppDirTimes(r, head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("walked history of %s at %s, %d app dirs", directory, head.Hash(), len(times))
+
+	c.hash = head.Hash()
+	c.times = times
+
+	return times, nil
+}
+
+// walkAppDirTimes walks the history from head newest first, like git log does,
+// and records for each app directory the first and the last commit changing it.
+// Merge commits are compared to their first parent, so changes reaching the
+// branch only through a merge, e.g. a conflict resolution, count too.
+func walkAppDirTimes(r *git.Repository, head plumbing.Hash) (map[string]appDirTimes, error) {
+	iter, err := r.Log(&git.LogOptions{
+		From:  head,
+		Order: git.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	times := make(map[string]appDirTimes)
+	err = iter.ForEach(func(c *object.Commit) error {
+		changed, err := changedAppDirsOfCommit(c)
+		if err != nil {
+			return err
+		}
+
+		when := c.Author.When.Unix()
+		for _, name := range changed {
+			t, ok := times[name]
+			if !ok {
+				t.updateTime = when
+			}
+			t.createTime = when
+			times[name] = t
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return times, nil
+}
+
+// changedAppDirsOfCommit returns the app directories a commit changed compared to its first parent.
+func changedAppDirsOfCommit(c *object.Commit) ([]string, error) {
+	dirs, err := appDirTrees(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentDirs map[string]plumbing.Hash
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+
+		parentDirs, err = appDirTrees(parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return diffAppDirTrees(parentDirs, dirs), nil
+}
+
+func GetCreateTimeSecond(dirPath, subDirPath string) (int64, error) {
+	times, err := getAppDirTimes(dirPath)
+	if err != nil {
+		return 0, err
+	}
+
+	t, ok := times[subDirPath]
+	if !ok {
+		return 0, fmt.Errorf("%s has no commit in %s", subDirPath, dirPath)
+	}
+
+	return t.createTime, nil
+}
+
+func GetLastUpdateTimeSecond(dirPath, subDirPath string) (int64, error) {
+	times, err := getAppDirTimes(dirPath)
+	if err != nil {
+		return 0, err
+	}
+
+	t, ok := times[subDirPath]
+	if !ok {
+		return 0, fmt.Errorf("%s has no commit in %s", subDirPath, dirPath)
+	}
+
+	return t.updateTime, nil
+}
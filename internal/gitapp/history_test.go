@@ -0,0 +1,149 @@
+package gitapp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testRepo is a git repository in a temporary directory with app folders at
+// its top level.
+type testRepo struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testRepo{t: t, dir: dir, repo: r}
+}
+
+// write writes the file of an app folder, name is relative to the repository.
+func (r *testRepo) write(name, content string) {
+	r.t.Helper()
+
+	p := filepath.Join(r.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) remove(name string) {
+	r.t.Helper()
+
+	if err := os.RemoveAll(filepath.Join(r.dir, filepath.FromSlash(name))); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit commits the whole worktree at when, on top of parents or HEAD.
+func (r *testRepo) commit(when time.Time, parents ...plumbing.Hash) plumbing.Hash {
+	r.t.Helper()
+
+	w, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := w.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		r.t.Fatal(err)
+	}
+
+	sig := &object.Signature{Name: "dev", Email: "dev@example.com", When: when}
+	hash, err := w.Commit("commit", &git.CommitOptions{
+		Author:            sig,
+		Committer:         sig,
+		Parents:           parents,
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	return hash
+}
+
+func TestWalkAppDirTimesMerge(t *testing.T) {
+	r := newTestRepo(t)
+	t1 := time.Unix(1000, 0)
+	t2 := time.Unix(2000, 0)
+	t3 := time.Unix(3000, 0)
+	t4 := time.Unix(4000, 0)
+
+	r.write("notes/Chart.yaml", "v1")
+	base := r.commit(t1)
+
+	// a side branch adds files
+	r.write("files/Chart.yaml", "v1")
+	side := r.commit(t2, base)
+
+	// the main branch adds photos
+	r.remove("files")
+	r.write("photos/Chart.yaml", "v1")
+	main := r.commit(t3, base)
+
+	// the merge brings files in and resolves a conflict in notes
+	r.write("files/Chart.yaml", "v1")
+	r.write("notes/Chart.yaml", "v2")
+	head := r.commit(t4, main, side)
+
+	times, err := walkAppDirTimes(r.repo, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]appDirTimes{
+		// changed only by the merge after its creation
+		"notes":  {createTime: t1.Unix(), updateTime: t4.Unix()},
+		"files":  {createTime: t2.Unix(), updateTime: t4.Unix()},
+		"photos": {createTime: t3.Unix(), updateTime: t3.Unix()},
+	}
+	for name, w := range want {
+		if got := times[name]; got != w {
+			t.Errorf("%s = %+v, want %+v", name, got, w)
+		}
+	}
+}
+
+func TestWalkAppDirTimesLinear(t *testing.T) {
+	r := newTestRepo(t)
+
+	r.write("notes/Chart.yaml", "v1")
+	r.write("files/Chart.yaml", "v1")
+	r.commit(time.Unix(1000, 0))
+	r.write("notes/Chart.yaml", "v2")
+	r.commit(time.Unix(2000, 0))
+	// a hidden directory is not an app
+	r.write(".github/workflow.yaml", "ci")
+	head := r.commit(time.Unix(3000, 0))
+
+	times, err := walkAppDirTimes(r.repo, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := times["notes"]; got.createTime != 1000 || got.updateTime != 2000 {
+		t.Errorf("notes = %+v", got)
+	}
+	if got := times["files"]; got.createTime != 1000 || got.updateTime != 1000 {
+		t.Errorf("files = %+v", got)
+	}
+	if _, ok := times[".github"]; ok {
+		t.Errorf("hidden directory recorded")
+	}
+}
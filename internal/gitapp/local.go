@@ -0,0 +1,424 @@
+package gitapp
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/golang/glog"
+)
+
+const (
+	SourceTypeGit   = "git"
+	SourceTypeLocal = "local"
+
+	// CatalogLocalPathEnv makes the default source a local directory or
+	// .tar.gz bundle of app folders instead of a git repository
+	CatalogLocalPathEnv = "CATALOG_LOCAL_PATH"
+
+	// LocalTimesFileName is an optional sidecar at the root of a local catalog
+	// holding the app times, e.g. {"firefox":{"createTime":1690000000,"updateTime":1700000000}}.
+	// Apps missing from it get the oldest and newest modification time of their files.
+	LocalTimesFileName = ".catalog-times.json"
+
+	localDigestFileName = ".catalog-digest"
+
+	// MaxBundleSize limits the size of an uploaded bundle
+	MaxBundleSize = 1 << 30
+	// MaxBundleExtractedSize and MaxBundleEntries limit what a bundle unpacks
+	// to, a small archive can expand to fill the disk
+	MaxBundleExtractedSize = 4 << 30
+	MaxBundleEntries       = 100000
+
+	// emptyBundleDigest is the digest of the empty catalog of a bundle source
+	// whose bundle was not provided yet
+	emptyBundleDigest = "empty"
+)
+
+var ErrNotLocalBundleSource = errors.New("source is not a local bundle source")
+
+// the bundle limits in force, tests lower them
+var (
+	maxBundleSize          int64 = MaxBundleSize
+	maxBundleExtractedSize int64 = MaxBundleExtractedSize
+	maxBundleEntries             = MaxBundleEntries
+)
+
+func getCatalogLocalPath() string {
+	return os.Getenv(CatalogLocalPathEnv)
+}
+
+func (s *Source) isLocal() bool {
+	return s.Type == SourceTypeLocal
+}
+
+// isBundle reports whether the source is a .tar.gz bundle, it is extracted
+// into the checkout directory of the source.
+func (s *Source) isBundle() bool {
+	return s.isLocal() && (strings.HasSuffix(s.Path, ".tar.gz") || strings.HasSuffix(s.Path, ".tgz"))
+}
+
+// head returns the commit of a git source, or the content digest of a local one.
+func (s *Source) head() (string, error) {
+	if !s.isLocal() {
+		return getGitLastCommitHash(s.Dir())
+	}
+
+	if s.isBundle() {
+		data, err := os.ReadFile(path.Join(s.Dir(), localDigestFileName))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	return localDirDigest(s.Dir())
+}
+
+// syncLocalSource extracts the bundle of s when it changed. Like a pull it
+// returns git.NoErrAlreadyUpToDate when nothing changed.
+func syncLocalSource(s *Source) error {
+	if !s.isBundle() {
+		info, err := os.Stat(s.Dir())
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("local source %s: %s is not a directory", s.Name, s.Dir())
+		}
+		// a plain directory is read in place, its digest tells if it changed
+		return nil
+	}
+
+	digest, err := fileDigest(s.Path)
+	if os.IsNotExist(err) {
+		return initEmptyBundleSource(s)
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := s.head()
+	if err == nil && current == digest {
+		return git.NoErrAlreadyUpToDate
+	}
+
+	tmp := s.Dir() + ".tmp"
+	err = os.RemoveAll(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = extractBundle(s.Path, tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("extract bundle of source %s: %w", s.Name, err)
+	}
+
+	err = os.WriteFile(path.Join(tmp, localDigestFileName), []byte(digest), 0644)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+
+	err = os.RemoveAll(s.Dir())
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, s.Dir())
+	if err != nil {
+		return err
+	}
+	glog.Infof("extracted bundle %s of source %s into %s, digest %s", s.Path, s.Name, s.Dir(), digest)
+
+	return nil
+}
+
+// initEmptyBundleSource serves an empty catalog for a bundle source whose
+// bundle is missing, e.g. an air-gapped install waiting for its first upload.
+// An already extracted bundle is kept.
+func initEmptyBundleSource(s *Source) error {
+	if _, err := s.head(); err == nil {
+		glog.Warningf("bundle %s of source %s is missing, keep the extracted catalog", s.Path, s.Name)
+		return git.NoErrAlreadyUpToDate
+	}
+
+	glog.Warningf("bundle %s of source %s is missing, serve an empty catalog until one is uploaded", s.Path, s.Name)
+	err := os.MkdirAll(s.Dir(), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join(s.Dir(), localDigestFileName), []byte(emptyBundleDigest), 0644)
+}
+
+// SaveBundle replaces the bundle of a local bundle source with an uploaded one,
+// it is ingested by the next update.
+func SaveBundle(name string, r io.Reader) (string, error) {
+	s := GetSource(name)
+	if s == nil {
+		return "", ErrSourceNotFound
+	}
+	if !s.isBundle() {
+		return "", ErrNotLocalBundleSource
+	}
+
+	err := os.MkdirAll(filepath.Dir(s.Path), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	upload := s.Path + ".upload"
+	f, err := os.Create(upload)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(upload)
+
+	n, err := io.Copy(f, io.LimitReader(r, maxBundleSize+1))
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+	if n > maxBundleSize {
+		return "", fmt.Errorf("bundle is larger than %d bytes", maxBundleSize)
+	}
+
+	// make sure the bundle is readable before it replaces the current one
+	check, err := os.MkdirTemp("", "bundle-check")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(check)
+
+	err = extractBundle(upload, check)
+	if err != nil {
+		return "", fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	err = os.Rename(upload, s.Path)
+	if err != nil {
+		return "", err
+	}
+
+	digest, err := fileDigest(s.Path)
+	if err != nil {
+		return "", err
+	}
+	glog.Infof("saved bundle of source %s, digest %s", s.Name, digest)
+
+	return digest, nil
+}
+
+// extractBundle unpacks the regular files and directories of a .tar.gz into
+// dir. Entries escaping dir are rejected, links are skipped. Bundles unpacking
+// to more than MaxBundleExtractedSize bytes or MaxBundleEntries entries are rejected.
+func extractBundle(bundle, dir string) error {
+	f, err := os.Open(bundle)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	var extracted int64
+	entries := 0
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entries++
+		if entries > maxBundleEntries {
+			return fmt.Errorf("bundle has more than %d entries", maxBundleEntries)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if hdr.Size < 0 || hdr.Size > maxBundleExtractedSize-extracted {
+				return fmt.Errorf("bundle unpacks to more than %d bytes", maxBundleExtractedSize)
+			}
+			extracted += hdr.Size
+		}
+
+		name := filepath.Clean(hdr.Name)
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid entry %s", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = extractBundleFile(tr, target, hdr)
+		default:
+			glog.Warningf("skip bundle entry %s of type %c", hdr.Name, hdr.Typeflag)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// keep the modification times, the app times fall back to them
+		err = os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractBundleFile(r io.Reader, target string, hdr *tar.Header) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.CopyN(f, r, hdr.Size)
+	return err
+}
+
+func fileDigest(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// localDirDigest hashes the paths, sizes and modification times of the files
+// of dir. It changes whenever a file is added, removed or written.
+func localDirDigest(dir string) (string, error) {
+	h := sha1.New()
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", rel, info.Size(), info.ModTime().UnixNano())
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type localAppTimes struct {
+	CreateTime int64 `json:"createTime"`
+	UpdateTime int64 `json:"updateTime"`
+}
+
+// getLocalAppTimes reads the times of an app of a local source from the
+// sidecar file, or from the modification times of its files.
+func getLocalAppTimes(d AppDir) (int64, int64, error) {
+	data, err := os.ReadFile(path.Join(d.Source.Dir(), LocalTimesFileName))
+	if err == nil {
+		times := make(map[string]localAppTimes)
+		if err := json.Unmarshal(data, &times); err != nil {
+			glog.Warningf("parse %s of source %s err:%s", LocalTimesFileName, d.Source.Name, err.Error())
+		} else if t, ok := times[d.Name]; ok {
+			return t.CreateTime, t.UpdateTime, nil
+		}
+	}
+
+	var mtimes []int64
+	err = filepath.WalkDir(d.Path(), func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			return nil
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		mtimes = append(mtimes, info.ModTime().Unix())
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(mtimes) == 0 {
+		return 0, 0, fmt.Errorf("%s has no files", d.Path())
+	}
+
+	sort.Slice(mtimes, func(i, j int) bool { return mtimes[i] < mtimes[j] })
+
+	return mtimes[0], mtimes[len(mtimes)-1], nil
+}
+
+// GetAppDirTimes returns the create and update time of an app, from the git
+// history for git sources and from file metadata for local ones.
+func GetAppDirTimes(d AppDir) (int64, int64, error) {
+	if d.Source.isLocal() {
+		return getLocalAppTimes(d)
+	}
+
+	createTime, err := GetCreateTimeSecond(d.Source.Dir(), d.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	updateTime, err := GetLastUpdateTimeSecond(d.Source.Dir(), d.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return createTime, updateTime, nil
+}
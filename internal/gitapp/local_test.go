@@ -0,0 +1,213 @@
+package gitapp
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bundleEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func file(name, content string) bundleEntry {
+	return bundleEntry{name: name, typeflag: tar.TypeReg, content: content}
+}
+
+func dir(name string) bundleEntry {
+	return bundleEntry{name: name, typeflag: tar.TypeDir}
+}
+
+func makeBundle(t *testing.T, entries ...bundleEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0644,
+			Size:     int64(len(e.content)),
+			ModTime:  time.Unix(1700000000, 0),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func writeBundle(t *testing.T, data []byte) string {
+	t.Helper()
+
+	p := filepath.Join(t.TempDir(), "catalog.tar.gz")
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+// setBundleLimits lowers the bundle limits for the test.
+func setBundleLimits(t *testing.T, size, extracted int64, entries int) {
+	savedSize, savedExtracted, savedEntries := maxBundleSize, maxBundleExtractedSize, maxBundleEntries
+	t.Cleanup(func() {
+		maxBundleSize, maxBundleExtractedSize, maxBundleEntries = savedSize, savedExtracted, savedEntries
+	})
+	maxBundleSize, maxBundleExtractedSize, maxBundleEntries = size, extracted, entries
+}
+
+func TestExtractBundle(t *testing.T) {
+	outside := t.TempDir()
+	bundle := writeBundle(t, makeBundle(t,
+		dir("notes/"),
+		file("notes/Chart.yaml", "name: notes"),
+		file("files/templates/deployment.yaml", "kind: Deployment"),
+		bundleEntry{name: "notes/escape", typeflag: tar.TypeSymlink, linkname: outside},
+	))
+
+	target := filepath.Join(t.TempDir(), "catalog")
+	if err := extractBundle(bundle, target); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(target, "notes", "Chart.yaml"))
+	if err != nil || string(data) != "name: notes" {
+		t.Errorf("notes/Chart.yaml = %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(target, "files", "templates", "deployment.yaml"))
+	if err != nil || !info.ModTime().Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("deployment.yaml = %v, %v, want the modification time of the entry", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(target, "notes", "escape")); !os.IsNotExist(err) {
+		t.Errorf("symlink entry extracted: %v", err)
+	}
+}
+
+func TestExtractBundleRejects(t *testing.T) {
+	setBundleLimits(t, MaxBundleSize, 1024, 4)
+
+	tests := []struct {
+		name    string
+		entries []bundleEntry
+		err     string
+	}{
+		{
+			name:    "parent directory entry",
+			entries: []bundleEntry{file("../evil", "x")},
+			err:     "invalid entry",
+		},
+		{
+			name:    "entry escaping through a subdirectory",
+			entries: []bundleEntry{file("notes/../../evil", "x")},
+			err:     "invalid entry",
+		},
+		{
+			name:    "absolute entry",
+			entries: []bundleEntry{file("/tmp/evil", "x")},
+			err:     "invalid entry",
+		},
+		{
+			name:    "file larger than the limit",
+			entries: []bundleEntry{file("notes/big", strings.Repeat("x", 1025))},
+			err:     "unpacks to more than",
+		},
+		{
+			name: "files adding up over the limit",
+			entries: []bundleEntry{
+				file("notes/a", strings.Repeat("x", 600)),
+				file("notes/b", strings.Repeat("x", 600)),
+			},
+			err: "unpacks to more than",
+		},
+		{
+			name: "too many entries",
+			entries: []bundleEntry{
+				dir("a/"), dir("b/"), dir("c/"), dir("d/"), dir("e/"),
+			},
+			err: "more than 4 entries",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			target := filepath.Join(parent, "catalog")
+
+			err := extractBundle(writeBundle(t, makeBundle(t, tt.entries...)), target)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			if _, err := os.Stat(filepath.Join(parent, "evil")); !os.IsNotExist(err) {
+				t.Errorf("entry written outside of the target: %v", err)
+			}
+		})
+	}
+
+	if err := extractBundle(writeBundle(t, []byte("not gzip")), t.TempDir()); err == nil {
+		t.Error("no error for a bundle that is not gzip")
+	}
+}
+
+func TestSaveBundle(t *testing.T) {
+	saved := sources
+	t.Cleanup(func() { sources = saved })
+	setBundleLimits(t, 4096, MaxBundleExtractedSize, MaxBundleEntries)
+
+	root := t.TempDir()
+	bundlePath := filepath.Join(root, "catalog.tar.gz")
+	sources = []*Source{
+		{Name: "offline", Type: SourceTypeLocal, Path: bundlePath},
+		{Name: "plain", Type: SourceTypeLocal, Path: root},
+	}
+
+	valid := makeBundle(t, file("notes/Chart.yaml", "name: notes"))
+	if _, err := SaveBundle("offline", bytes.NewReader(valid)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(bundlePath); err != nil || !bytes.Equal(data, valid) {
+		t.Fatalf("saved bundle differs: %v", err)
+	}
+
+	// a rejected upload keeps the current bundle
+	rejected := [][]byte{
+		makeBundle(t, file("../evil", "x")),
+		bytes.Repeat([]byte("x"), 4097),
+	}
+	for _, data := range rejected {
+		if _, err := SaveBundle("offline", bytes.NewReader(data)); err == nil {
+			t.Error("no error for a rejected bundle")
+		}
+	}
+	if data, err := os.ReadFile(bundlePath); err != nil || !bytes.Equal(data, valid) {
+		t.Errorf("rejected upload replaced the bundle: %v", err)
+	}
+
+	if _, err := SaveBundle("plain", bytes.NewReader(valid)); err != ErrNotLocalBundleSource {
+		t.Errorf("bundle of a directory source err = %v", err)
+	}
+	if _, err := SaveBundle("missing", bytes.NewReader(valid)); err != ErrSourceNotFound {
+		t.Errorf("bundle of a missing source err = %v", err)
+	}
+}
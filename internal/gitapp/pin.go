@@ -0,0 +1,274 @@
+package gitapp
+
+import (
+	"app-store-server/internal/mongo"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/golang/glog"
+)
+
+const (
+	// GitPinRefEnv pins the default source to a tag or commit, GIT_SOURCES
+	// entries use their "pin" field instead
+	GitPinRefEnv = "GIT_PIN_REF"
+
+	// RollbackDefaultRef is rolled back to when no ref is given
+	RollbackDefaultRef = "HEAD~1"
+)
+
+var ErrSourceNotFound = errors.New("catalog source not found")
+
+// checkoutMu serializes the operations changing the local checkouts, the
+// periodic pull and the admin pin requests may run at the same time.
+var checkoutMu sync.Mutex
+
+// PinStatus describes what a source checkout follows.
+type PinStatus struct {
+	Source string `json:"source"`
+	Branch string `json:"branch"`
+	// Pin is the tag or commit the checkout is held at, empty when it tracks the branch
+	Pin  string `json:"pin"`
+	Head string `json:"head"`
+}
+
+// pinnedRef returns the ref the source is pinned to, or "" when it tracks its
+// branch. A pin or unpin made through the admin api overrides the configuration.
+func pinnedRef(s *Source) string {
+	if s.isLocal() {
+		return ""
+	}
+
+	pins, err := mongo.GetSourcePinsFromDB()
+	if err != nil {
+		glog.Warningf("GetSourcePinsFromDB err:%s", err.Error())
+	}
+
+	if ref, ok := pins[s.Name]; ok {
+		return ref
+	}
+
+	return s.Pin
+}
+
+func getPinRef() string {
+	return os.Getenv(GitPinRefEnv)
+}
+
+// GetPinStatus returns the pin state of every source.
+func GetPinStatus() []*PinStatus {
+	list := make([]*PinStatus, 0, len(sources))
+	for _, s := range sources {
+		head, err := s.head()
+		if err != nil {
+			glog.Warningf("head of source %s err:%s", s.Name, err.Error())
+		}
+
+		list = append(list, &PinStatus{
+			Source: s.Name,
+			Branch: s.Branch,
+			Pin:    pinnedRef(s),
+			Head:   head,
+		})
+	}
+
+	return list
+}
+
+// PinSource holds the source at ref, a tag or a commit. The ref is kept as is,
+// so a moved tag is followed on the next update.
+func PinSource(name, ref string) (string, error) {
+	s := GetSource(name)
+	if s == nil {
+		return "", ErrSourceNotFound
+	}
+
+	hash, err := resolveSourceRef(s, ref)
+	if err != nil {
+		return "", err
+	}
+
+	err = mongo.SetSourcePinToDB(s.Name, ref)
+	if err != nil {
+		return "", err
+	}
+	glog.Infof("source %s pinned to %s (%s)", s.Name, ref, hash)
+
+	return hash, nil
+}
+
+// RollbackSource pins the source to the commit ref resolves to now, HEAD~1
+// when ref is empty, so the catalog goes back to it on the next update.
+func RollbackSource(name, ref string) (string, error) {
+	s := GetSource(name)
+	if s == nil {
+		return "", ErrSourceNotFound
+	}
+
+	if ref == "" {
+		ref = RollbackDefaultRef
+	}
+
+	hash, err := resolveSourceRef(s, ref)
+	if err != nil {
+		return "", err
+	}
+
+	err = mongo.SetSourcePinToDB(s.Name, hash)
+	if err != nil {
+		return "", err
+	}
+	glog.Infof("source %s rolled back to %s (%s)", s.Name, ref, hash)
+
+	return hash, nil
+}
+
+// UnpinSource makes the source track its branch again, also when the
+// configuration pins it.
+func UnpinSource(name string) error {
+	s := GetSource(name)
+	if s == nil {
+		return ErrSourceNotFound
+	}
+
+	err := mongo.SetSourcePinToDB(s.Name, "")
+	if err != nil {
+		return err
+	}
+	glog.Infof("source %s unpinned, tracking %s", s.Name, s.Branch)
+
+	return nil
+}
+
+func resolveSourceRef(s *Source, ref string) (string, error) {
+	if s.isLocal() {
+		return "", fmt.Errorf("source %s is not a git source", s.Name)
+	}
+
+	checkoutMu.Lock()
+	defer checkoutMu.Unlock()
+
+	r, err := git.PlainOpen(s.Dir())
+	if err != nil {
+		return "", err
+	}
+
+	fetchSource(r, s)
+
+	hash, err := resolveRef(r, ref)
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+// fetchSource fetches branches and tags, failures are only logged so that
+// refs already known locally still resolve.
+func fetchSource(r *git.Repository, s *Source) {
+	auth, err := s.authMethod()
+	if err != nil {
+		glog.Warningf("git fetch of %s failed: %s", s.Name, err.Error())
+		return
+	}
+
+	err = r.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		Tags:       git.AllTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		glog.Warningf("git fetch failed: %s", err.Error())
+	}
+}
+
+func resolveRef(r *git.Repository, ref string) (plumbing.Hash, error) {
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("resolve %s: %w", ref, err)
+	}
+
+	// make sure the ref points to a commit we have
+	commit, err := r.CommitObject(*hash)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("resolve %s: %w", ref, err)
+	}
+
+	return commit.Hash, nil
+}
+
+// checkoutPin moves the checkout of s to a detached HEAD at ref. Like a pull it
+// returns git.NoErrAlreadyUpToDate when HEAD is already there.
+func checkoutPin(s *Source, ref string) error {
+	r, err := git.PlainOpen(s.Dir())
+	if err != nil {
+		return err
+	}
+
+	fetchSource(r, s)
+
+	hash, err := resolveRef(r, ref)
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err == nil && head.Name() == plumbing.HEAD && head.Hash() == hash {
+		return git.NoErrAlreadyUpToDate
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	err = w.Checkout(&git.CheckoutOptions{
+		Hash:  hash,
+		Force: true,
+	})
+	if err != nil {
+		return fmt.Errorf("checkout %s: %w", ref, err)
+	}
+	glog.Infof("source %s checked out at %s (%s)", s.Name, ref, hash)
+
+	return nil
+}
+
+// checkoutBranch brings a checkout left detached by a pin back to its branch.
+func checkoutBranch(s *Source) error {
+	r, err := git.PlainOpen(s.Dir())
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	branch := plumbing.NewBranchReferenceName(s.Branch)
+	if head.Name() == branch {
+		return nil
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	err = w.Checkout(&git.CheckoutOptions{
+		Branch: branch,
+		Force:  true,
+	})
+	if err != nil {
+		return fmt.Errorf("checkout %s: %w", s.Branch, err)
+	}
+	glog.Infof("source %s back on branch %s", s.Name, s.Branch)
+
+	return nil
+}
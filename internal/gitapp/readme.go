@@ -0,0 +1,153 @@
+package gitapp
+
+import (
+	"app-store-server/internal/constants"
+	"app-store-server/internal/i18n"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrAssetNotFound = errors.New("asset not found")
+
+// assetExtensions are the media files FindAppAsset serves, the rest of an app
+// directory, manifests, values and templates, is not public
+var assetExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".webp": true,
+	".avif": true,
+	".bmp":  true,
+	".ico":  true,
+	".svg":  true,
+	".mp4":  true,
+	".webm": true,
+	".mp3":  true,
+	".ogg":  true,
+	".wav":  true,
+}
+
+// Readme is the README of an app picked for the requested languages.
+type Readme struct {
+	Content []byte
+	// Dir is the directory of the file relative to the app directory, its
+	// relative links are resolved from there
+	Dir string
+	// Lang is the locale of the file, empty for the base README.md
+	Lang string
+}
+
+// readmeLocales returns the localized READMEs of an app directory by locale,
+// README.<lang>.md in the app directory or i18n/<lang>/README.md.
+func readmeLocales(appPath string) map[string]string {
+	files := make(map[string]string)
+
+	ext := path.Ext(constants.ReadmeFileName)
+	base := strings.TrimSuffix(constants.ReadmeFileName, ext)
+	matches, _ := filepath.Glob(filepath.Join(appPath, base+".*"+ext))
+	for _, m := range matches {
+		lang := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), base+"."), ext)
+		if lang != "" {
+			files[lang] = filepath.Base(m)
+		}
+	}
+
+	// i18n/<lang>/README.md wins over README.<lang>.md, it sits with the other translations
+	entries, _ := os.ReadDir(filepath.Join(appPath, "i18n"))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		name := path.Join("i18n", e.Name(), constants.ReadmeFileName)
+		if _, err := os.Stat(filepath.Join(appPath, name)); err == nil {
+			files[e.Name()] = name
+		}
+	}
+
+	return files
+}
+
+// FindReadMe returns the README of an app in the first requested language it
+// is translated to, or the base README.md.
+func FindReadMe(name string, langs []string) (*Readme, error) {
+	appDir, exist := FindAppDir(name)
+	if !exist {
+		return nil, fmt.Errorf("%s not exist", name)
+	}
+
+	file := constants.ReadmeFileName
+	lang := ""
+	if len(langs) > 0 {
+		locales := readmeLocales(appDir.Path())
+		available := make([]string, 0, len(locales))
+		for l := range locales {
+			available = append(available, l)
+		}
+		sort.Strings(available)
+
+		if chain := i18n.Chain(langs, available); len(chain) > 0 {
+			lang = chain[0]
+			file = locales[lang]
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(appDir.Path(), file))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Readme{
+		Content: content,
+		Dir:     path.Dir(file),
+		Lang:    lang,
+	}, nil
+}
+
+// FindAppAsset returns the local path of an image or media file of an app
+// directory. Other files, hidden files and paths leaving the app directory,
+// also through a symlink, are not found.
+func FindAppAsset(name, assetPath string) (string, error) {
+	appDir, exist := FindAppDir(name)
+	if !exist {
+		return "", fmt.Errorf("%s not exist", name)
+	}
+
+	clean := path.Clean("/" + assetPath)
+	if clean == "/" || strings.Contains(clean, "/.") {
+		return "", ErrAssetNotFound
+	}
+	if !assetExtensions[strings.ToLower(path.Ext(clean))] {
+		return "", ErrAssetNotFound
+	}
+
+	root, err := filepath.EvalSymlinks(appDir.Path())
+	if err != nil {
+		return "", err
+	}
+
+	p, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(clean)))
+	if err != nil {
+		return "", ErrAssetNotFound
+	}
+	if !strings.HasPrefix(p, root+string(filepath.Separator)) {
+		return "", ErrAssetNotFound
+	}
+
+	// a symlink may point at a file of another kind
+	if !assetExtensions[strings.ToLower(filepath.Ext(p))] {
+		return "", ErrAssetNotFound
+	}
+
+	info, err := os.Stat(p)
+	if err != nil || info.IsDir() {
+		return "", ErrAssetNotFound
+	}
+
+	return p, nil
+}
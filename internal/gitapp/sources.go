@@ -0,0 +1,288 @@
+package gitapp
+
+import (
+	"app-store-server/internal/constants"
+	"app-store-server/pkg/utils"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// GitSourcesEnv holds a JSON list of catalog sources, e.g.
+	// [{"name":"default","url":"https://github.com/Above-Os/terminus-apps.git","branch":"main"},
+	//  {"name":"internal","url":"https://git.example.com/apps.git","branch":"main","priority":10,"prefix":"int-"}]
+	// When it is not set the catalog has a single source read from GIT_ADDR/GIT_BRANCH.
+	GitSourcesEnv = "GIT_SOURCES"
+
+	// DefaultSourceName is the source checked out into constants.AppGitLocalDir
+	DefaultSourceName = "default"
+)
+
+var sourceNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9_-]*[a-z0-9])?$`)
+
+// Source is a git repository the catalog is built from.
+type Source struct {
+	Name string `json:"name"`
+	// Type is "git", the default, or "local"
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Branch string `json:"branch"`
+	// Path is the directory or .tar.gz bundle of a local source
+	Path string `json:"path"`
+	// Priority decides which source wins when several provide an app with the same name, higher wins
+	Priority int `json:"priority"`
+	// Prefix is prepended to the names of the apps of this source
+	Prefix string `json:"prefix"`
+	// Pin holds the checkout at a tag or commit instead of the branch tip
+	Pin string `json:"pin"`
+	// Auth holds the credentials of a private repository
+	Auth *SourceAuth `json:"auth"`
+
+	// order is the position of the source in the configuration, it breaks priority ties
+	order int
+}
+
+// Dir returns the local checkout directory of the source. A local directory
+// source is read in place.
+func (s *Source) Dir() string {
+	if s.isLocal() && !s.isBundle() {
+		return s.Path
+	}
+
+	if s.Name == DefaultSourceName {
+		return constants.AppGitLocalDir
+	}
+
+	return path.Join(constants.AppGitSourcesDir, s.Name)
+}
+
+// ChartDir returns the directory the charts of the source are packaged into.
+func (s *Source) ChartDir() string {
+	if s.Name == DefaultSourceName {
+		return constants.AppGitZipLocalDir
+	}
+
+	return path.Join(constants.AppGitZipLocalDir, s.Name)
+}
+
+// ChartName returns the chart file name relative to constants.AppGitZipLocalDir.
+func (s *Source) ChartName(fileName string) string {
+	if s.Name == DefaultSourceName {
+		return fileName
+	}
+
+	return path.Join(s.Name, fileName)
+}
+
+// Outranks reports whether the apps of s win over the apps of o on a name collision.
+func (s *Source) Outranks(o *Source) bool {
+	if s.Priority != o.Priority {
+		return s.Priority > o.Priority
+	}
+
+	return s.order < o.order
+}
+
+var sources []*Source
+
+// Sources returns the configured sources, highest priority first.
+func Sources() []*Source {
+	return sources
+}
+
+func GetSource(name string) *Source {
+	for _, s := range sources {
+		if s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func loadSources() error {
+	list, err := parseSources(os.Getenv(GitSourcesEnv))
+	if err != nil {
+		return err
+	}
+
+	sources = list
+	for _, s := range sources {
+		if s.isLocal() {
+			glog.Infof("catalog source %s: local %s priority:%d prefix:%q dir:%s", s.Name, s.Path, s.Priority, s.Prefix, s.Dir())
+			continue
+		}
+		glog.Infof("catalog source %s: %s@%s priority:%d prefix:%q pin:%q auth:%t dir:%s", s.Name, redactURL(s.URL), s.Branch, s.Priority, s.Prefix, s.Pin, s.hasAuth(), s.Dir())
+	}
+
+	return nil
+}
+
+func parseSources(config string) ([]*Source, error) {
+	if strings.TrimSpace(config) == "" {
+		if localPath := getCatalogLocalPath(); localPath != "" {
+			return []*Source{{
+				Name: DefaultSourceName,
+				Type: SourceTypeLocal,
+				Path: localPath,
+			}}, nil
+		}
+
+		auth, err := getSourceAuthFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s or %s: %w", GitTokenFileEnv, GitSSHKeyFileEnv, err)
+		}
+
+		return []*Source{{
+			Name:   DefaultSourceName,
+			Type:   SourceTypeGit,
+			URL:    getGitAddr(),
+			Branch: getGitBranch(),
+			Pin:    getPinRef(),
+			Auth:   auth,
+		}}, nil
+	}
+
+	var list []*Source
+	if err := json.Unmarshal([]byte(config), &list); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", GitSourcesEnv, err)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("invalid %s: no source", GitSourcesEnv)
+	}
+
+	names := make(map[string]bool)
+	for i, s := range list {
+		if !sourceNameRegex.MatchString(s.Name) {
+			return nil, fmt.Errorf("invalid %s: bad source name %q", GitSourcesEnv, s.Name)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("invalid %s: duplicate source name %q", GitSourcesEnv, s.Name)
+		}
+		names[s.Name] = true
+
+		switch s.Type {
+		case "", SourceTypeGit:
+			s.Type = SourceTypeGit
+			if s.URL == "" {
+				return nil, fmt.Errorf("invalid %s: source %s has no url", GitSourcesEnv, s.Name)
+			}
+		case SourceTypeLocal:
+			if s.Path == "" {
+				return nil, fmt.Errorf("invalid %s: source %s has no path", GitSourcesEnv, s.Name)
+			}
+		default:
+			return nil, fmt.Errorf("invalid %s: source %s has unknown type %q", GitSourcesEnv, s.Name, s.Type)
+		}
+		if s.Branch == "" {
+			s.Branch = AppGitBranch
+		}
+		if err := s.Auth.validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s: source %s auth: %w", GitSourcesEnv, s.Name, err)
+		}
+		s.order = i
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Outranks(list[j])
+	})
+
+	return list, nil
+}
+
+// AppDir is an app directory inside the checkout of a source.
+type AppDir struct {
+	Source *Source
+	Name   string
+}
+
+func (d AppDir) Path() string {
+	return path.Join(d.Source.Dir(), d.Name)
+}
+
+// AppName returns the name the app is published under.
+func (d AppDir) AppName() string {
+	return d.Source.Prefix + d.Name
+}
+
+// ListAppDirs collects the app directories of all sources, hidden directories
+// and withheld sources are skipped.
+func ListAppDirs() ([]AppDir, error) {
+	appDirs := []AppDir{}
+	for _, s := range sources {
+		if isWithheld(s) {
+			continue
+		}
+		entries, err := os.ReadDir(s.Dir())
+		if err != nil {
+			glog.Warningf("read dir %s error: %s", s.Dir(), err.Error())
+			return nil, err
+		}
+
+		for _, e := range entries {
+			if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			appDirs = append(appDirs, AppDir{Source: s, Name: e.Name()})
+		}
+	}
+
+	return appDirs, nil
+}
+
+// FindAppDir looks up the directory an app is published from. Sources are
+// searched by priority, so the result matches the app kept on a name collision.
+func FindAppDir(name string) (AppDir, bool) {
+	for _, s := range sources {
+		if !strings.HasPrefix(name, s.Prefix) || isWithheld(s) {
+			continue
+		}
+
+		d := AppDir{Source: s, Name: strings.TrimPrefix(name, s.Prefix)}
+		if d.Name == "" || strings.Contains(d.Name, "/") || strings.HasPrefix(d.Name, ".") {
+			continue
+		}
+
+		exist, err := utils.PathExists(d.Path())
+		if err != nil {
+			glog.Warningf("utils.PathExists %s %s", d.Path(), err.Error())
+		}
+		if exist {
+			return d, true
+		}
+	}
+
+	return AppDir{}, false
+}
+
+// catalogHash combines the HEAD commits of all sources into the hash the apps
+// are tagged with. With a single source it is the HEAD commit of that source.
+func catalogHash(heads map[string]string) string {
+	if len(heads) == 1 {
+		for _, hash := range heads {
+			return hash
+		}
+	}
+
+	names := make([]string, 0, len(heads))
+	for name := range heads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha1.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s=%s\n", name, heads[name])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,158 @@
+package gitapp
+
+import (
+	"app-store-server/internal/constants"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func sourceNames(list []*Source) []string {
+	var names []string
+	for _, s := range list {
+		names = append(names, s.Name)
+	}
+
+	return names
+}
+
+func TestParseSources(t *testing.T) {
+	list, err := parseSources(`[
+		{"name":"public","url":"https://github.com/Above-Os/apps.git"},
+		{"name":"internal","url":"https://git.example.com/apps.git","branch":"release","priority":10,"prefix":"int-"},
+		{"name":"mirror","url":"https://git.example.com/mirror.git"},
+		{"name":"local","type":"local","path":"/srv/apps"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// highest priority first, ties in configuration order
+	if got, want := sourceNames(list), []string{"internal", "public", "mirror", "local"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("sources = %v, want %v", got, want)
+	}
+	if list[1].Type != SourceTypeGit || list[1].Branch != AppGitBranch {
+		t.Errorf("public defaults = %+v", list[1])
+	}
+	if list[0].Branch != "release" || list[0].Prefix != "int-" {
+		t.Errorf("internal = %+v", list[0])
+	}
+}
+
+func TestParseSourcesErrors(t *testing.T) {
+	tests := map[string]string{
+		"not json":           `{`,
+		"empty list":         `[]`,
+		"bad name":           `[{"name":"Public","url":"https://example.com/apps.git"}]`,
+		"duplicate name":     `[{"name":"a","url":"https://example.com/a.git"},{"name":"a","url":"https://example.com/b.git"}]`,
+		"git without url":    `[{"name":"a"}]`,
+		"local without path": `[{"name":"a","type":"local"}]`,
+		"unknown type":       `[{"name":"a","type":"svn","url":"https://example.com/a"}]`,
+		"exclusive auth":     `[{"name":"a","url":"https://example.com/a.git","auth":{"tokenFile":"/t","sshKeyFile":"/k"}}]`,
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseSources(config); err == nil {
+				t.Errorf("no error for %s", config)
+			}
+		})
+	}
+}
+
+func TestParseSourcesDefault(t *testing.T) {
+	t.Setenv(CatalogLocalPathEnv, "")
+	t.Setenv(GitAddrEnv, "https://git.example.com/apps.git")
+	t.Setenv(GitBranchEnv, "dev")
+
+	list, err := parseSources(" ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("sources = %v", sourceNames(list))
+	}
+
+	s := list[0]
+	if s.Name != DefaultSourceName || s.URL != "https://git.example.com/apps.git" || s.Branch != "dev" {
+		t.Errorf("default source = %+v", s)
+	}
+	// the default source keeps the directories of a single repository catalog
+	if s.Dir() != constants.AppGitLocalDir || s.ChartName("notes-1.0.0.tgz") != "notes-1.0.0.tgz" {
+		t.Errorf("default source dir = %s, chart name = %s", s.Dir(), s.ChartName("notes-1.0.0.tgz"))
+	}
+}
+
+func TestSourceOutranks(t *testing.T) {
+	low := &Source{Name: "low", Priority: 1, order: 0}
+	high := &Source{Name: "high", Priority: 5, order: 1}
+	tie := &Source{Name: "tie", Priority: 5, order: 2}
+
+	if !high.Outranks(low) || low.Outranks(high) {
+		t.Error("priority does not decide")
+	}
+	if !high.Outranks(tie) || tie.Outranks(high) {
+		t.Error("configuration order does not break the tie")
+	}
+}
+
+func TestFindAppDir(t *testing.T) {
+	saved := sources
+	t.Cleanup(func() { sources = saved })
+
+	internalDir := t.TempDir()
+	publicDir := t.TempDir()
+	for _, dir := range []string{
+		filepath.Join(internalDir, "notes"),
+		filepath.Join(publicDir, "notes"),
+		filepath.Join(publicDir, "files"),
+		filepath.Join(publicDir, ".github"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	internal := &Source{Name: "internal", Type: SourceTypeLocal, Path: internalDir, Priority: 10}
+	public := &Source{Name: "public", Type: SourceTypeLocal, Path: publicDir}
+	sources = []*Source{internal, public}
+
+	dirs, err := ListAppDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range dirs {
+		names = append(names, d.Source.Name+"/"+d.AppName())
+	}
+	if want := []string{"internal/notes", "public/files", "public/notes"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("app dirs = %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		name   string
+		source *Source
+	}{
+		{name: "notes", source: internal},
+		{name: "files", source: public},
+		{name: "music"},
+		{name: ".github"},
+		{name: "../notes"},
+	}
+	for _, tt := range tests {
+		d, ok := FindAppDir(tt.name)
+		if ok != (tt.source != nil) || (ok && d.Source != tt.source) {
+			t.Errorf("FindAppDir(%q) = %v %v, want source %v", tt.name, d.Source, ok, tt.source)
+		}
+	}
+
+	// with a prefix the apps of the sources no longer collide
+	internal.Prefix = "int-"
+	if d, ok := FindAppDir("int-notes"); !ok || d.Source != internal {
+		t.Errorf("FindAppDir(int-notes) = %v %v", d.Source, ok)
+	}
+	if d, ok := FindAppDir("notes"); !ok || d.Source != public {
+		t.Errorf("FindAppDir(notes) = %v %v", d.Source, ok)
+	}
+}
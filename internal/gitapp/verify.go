@@ -0,0 +1,408 @@
+package gitapp
+
+import (
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/models"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// GitVerifyEnv enables commit signature verification after a pull:
+	// "head" checks the new HEAD, "all" every commit not reachable from the
+	// last accepted one. Empty disables verification.
+	GitVerifyEnv = "GIT_VERIFY_COMMITS"
+
+	// GitTrustedGPGKeysFileEnv is an armored public keyring of trusted signers
+	GitTrustedGPGKeysFileEnv = "GIT_TRUSTED_GPG_KEYS_FILE"
+	// GitTrustedSSHKeysFileEnv lists trusted ssh signing keys in
+	// authorized_keys or allowed_signers format
+	GitTrustedSSHKeysFileEnv = "GIT_TRUSTED_SSH_KEYS_FILE"
+
+	VerifyHead = "head"
+	VerifyAll  = "all"
+
+	sshSignatureArmorStart = "-----BEGIN SSH SIGNATURE-----"
+	sshSignatureArmorEnd   = "-----END SSH SIGNATURE-----"
+	sshSignatureMagic      = "SSHSIG"
+	sshSignatureNamespace  = "git"
+)
+
+var ErrUntrustedCommit = errors.New("untrusted commit")
+
+// withheld holds the sources without an accepted commit whose HEAD failed
+// verification, their apps are not listed until a commit of them is accepted
+var (
+	withheldMu sync.RWMutex
+	withheld   = make(map[string]bool)
+)
+
+func getVerifyMode() string {
+	return strings.ToLower(os.Getenv(GitVerifyEnv))
+}
+
+// VerifyFailures returns the latest rejection of the sources whose latest pull
+// was rejected, as recorded by the leader.
+func VerifyFailures() []models.CommitRejection {
+	rejections, err := mongo.GetCommitRejections()
+	if err != nil {
+		return []models.CommitRejection{}
+	}
+
+	bySource := make(map[string]*models.CommitRejection, len(rejections))
+	for _, r := range rejections {
+		bySource[r.Source] = r
+	}
+
+	list := make([]models.CommitRejection, 0, len(rejections))
+	for _, s := range sources {
+		if r, ok := bySource[s.Name]; ok {
+			list = append(list, *r)
+		}
+	}
+
+	return list
+}
+
+func setVerifyFailure(s *Source, f *models.CommitRejection) {
+	if f == nil {
+		_ = mongo.DeleteCommitRejection(s.Name)
+		return
+	}
+	_ = mongo.UpsertCommitRejection(f)
+}
+
+func setWithheld(s *Source, w bool) {
+	withheldMu.Lock()
+	defer withheldMu.Unlock()
+
+	if w {
+		withheld[s.Name] = true
+		return
+	}
+	delete(withheld, s.Name)
+}
+
+func isWithheld(s *Source) bool {
+	withheldMu.RLock()
+	defer withheldMu.RUnlock()
+
+	return withheld[s.Name]
+}
+
+// acceptSourceHead verifies the HEAD of s against the last accepted commit.
+// An untrusted HEAD is reset to the accepted commit, so the previous catalog
+// stays served, and ErrUntrustedCommit is returned. Without an accepted commit
+// the source is withheld instead, it serves no apps.
+func acceptSourceHead(s *Source, accepted string) error {
+	mode := getVerifyMode()
+	if mode == "" || s.isLocal() {
+		return nil
+	}
+
+	r, err := git.PlainOpen(s.Dir())
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if head.Hash().String() == accepted {
+		return nil
+	}
+
+	verifyErr := verifyCommits(r, mode, head.Hash(), accepted)
+	if verifyErr == nil {
+		setWithheld(s, false)
+		setVerifyFailure(s, nil)
+		return nil
+	}
+
+	glog.Warningf("source %s commit %s rejected: %s", s.Name, head.Hash(), verifyErr.Error())
+	setVerifyFailure(s, &models.CommitRejection{
+		Source:   s.Name,
+		Commit:   head.Hash().String(),
+		Accepted: accepted,
+		Reason:   verifyErr.Error(),
+		Time:     time.Now().Unix(),
+	})
+
+	if accepted == "" {
+		setWithheld(s, true)
+		return fmt.Errorf("%w: %s, no verified commit to fall back to", ErrUntrustedCommit, verifyErr.Error())
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	err = w.Reset(&git.ResetOptions{
+		Commit: plumbing.NewHash(accepted),
+		Mode:   git.HardReset,
+	})
+	if err != nil {
+		return fmt.Errorf("reset %s to %s: %w", s.Name, accepted, err)
+	}
+
+	return fmt.Errorf("%w: %s", ErrUntrustedCommit, verifyErr.Error())
+}
+
+// verifyCommits checks head, or with mode "all" every commit reachable from
+// head but not from accepted.
+func verifyCommits(r *git.Repository, mode string, head plumbing.Hash, accepted string) error {
+	keys, err := loadTrustedKeys()
+	if err != nil {
+		return err
+	}
+
+	if mode != VerifyAll || accepted == "" {
+		commit, err := r.CommitObject(head)
+		if err != nil {
+			return err
+		}
+		return keys.verify(commit)
+	}
+
+	known, err := ancestors(r, plumbing.NewHash(accepted))
+	if err != nil {
+		return err
+	}
+
+	iter, err := r.Log(&git.LogOptions{From: head})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	return iter.ForEach(func(c *object.Commit) error {
+		if known[c.Hash] {
+			return nil
+		}
+		return keys.verify(c)
+	})
+}
+
+func ancestors(r *git.Repository, from plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	iter, err := r.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	known := make(map[plumbing.Hash]bool)
+	err = iter.ForEach(func(c *object.Commit) error {
+		known[c.Hash] = true
+		return nil
+	})
+
+	return known, err
+}
+
+type trustedKeys struct {
+	gpgKeyRing string
+	sshKeys    []ssh.PublicKey
+}
+
+// loadTrustedKeys reads the allowlists on every verification so that key
+// rotations need no restart.
+func loadTrustedKeys() (*trustedKeys, error) {
+	keys := &trustedKeys{}
+
+	if name := os.Getenv(GitTrustedGPGKeysFileEnv); name != "" {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		keys.gpgKeyRing = string(data)
+	}
+
+	if name := os.Getenv(GitTrustedSSHKeysFileEnv); name != "" {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		keys.sshKeys = parseSSHAllowlist(data)
+	}
+
+	if keys.gpgKeyRing == "" && len(keys.sshKeys) == 0 {
+		return nil, fmt.Errorf("no trusted keys, set %s or %s", GitTrustedGPGKeysFileEnv, GitTrustedSSHKeysFileEnv)
+	}
+
+	return keys, nil
+}
+
+// parseSSHAllowlist accepts authorized_keys lines and allowed_signers lines,
+// which start with the principal.
+func parseSSHAllowlist(data []byte) []ssh.PublicKey {
+	var keys []ssh.PublicKey
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			if i := strings.IndexAny(line, " \t"); i > 0 {
+				key, _, _, _, err = ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(line[i:])))
+			}
+		}
+		if err != nil {
+			glog.Warningf("skip invalid trusted ssh key line: %s", err.Error())
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func (k *trustedKeys) verify(c *object.Commit) error {
+	if c.PGPSignature == "" {
+		return fmt.Errorf("commit %s is not signed", c.Hash)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(c.PGPSignature), sshSignatureArmorStart) {
+		if len(k.sshKeys) == 0 {
+			return fmt.Errorf("commit %s is ssh signed, no trusted ssh keys", c.Hash)
+		}
+		err := k.verifySSH(c)
+		if err != nil {
+			return fmt.Errorf("commit %s: %w", c.Hash, err)
+		}
+		return nil
+	}
+
+	if k.gpgKeyRing == "" {
+		return fmt.Errorf("commit %s is gpg signed, no trusted gpg keys", c.Hash)
+	}
+	_, err := c.Verify(k.gpgKeyRing)
+	if err != nil {
+		return fmt.Errorf("commit %s: %w", c.Hash, err)
+	}
+
+	return nil
+}
+
+// verifySSH checks an ssh signature as described in OpenSSH PROTOCOL.sshsig.
+func (k *trustedKeys) verifySSH(c *object.Commit) error {
+	sig, err := parseSSHSignature(c.PGPSignature)
+	if err != nil {
+		return err
+	}
+
+	if sig.Namespace != sshSignatureNamespace {
+		return fmt.Errorf("unexpected ssh signature namespace %q", sig.Namespace)
+	}
+
+	pub, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	trusted := false
+	for _, key := range k.sshKeys {
+		if bytes.Equal(key.Marshal(), pub.Marshal()) {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return fmt.Errorf("ssh key %s is not trusted", ssh.FingerprintSHA256(pub))
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported ssh signature hash %q", sig.HashAlgorithm)
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := c.EncodeWithoutSignature(encoded); err != nil {
+		return err
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, reader); err != nil {
+		return err
+	}
+
+	signed := append([]byte(sshSignatureMagic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          string
+	}{
+		Namespace:     sig.Namespace,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          string(h.Sum(nil)),
+	})...)
+
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal(sig.Signature, signature); err != nil {
+		return err
+	}
+
+	return pub.Verify(signed, signature)
+}
+
+type sshSignature struct {
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+func parseSSHSignature(armored string) (*sshSignature, error) {
+	body := strings.TrimSpace(armored)
+	body = strings.TrimPrefix(body, sshSignatureArmorStart)
+	body = strings.TrimSuffix(body, sshSignatureArmorEnd)
+	body = strings.Join(strings.Fields(body), "")
+
+	blob, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode ssh signature: %w", err)
+	}
+
+	if len(blob) < len(sshSignatureMagic)+4 || string(blob[:len(sshSignatureMagic)]) != sshSignatureMagic {
+		return nil, errors.New("invalid ssh signature")
+	}
+	blob = blob[len(sshSignatureMagic):]
+
+	if version := binary.BigEndian.Uint32(blob); version != 1 {
+		return nil, fmt.Errorf("unsupported ssh signature version %d", version)
+	}
+
+	sig := &sshSignature{}
+	if err := ssh.Unmarshal(blob[4:], sig); err != nil {
+		return nil, fmt.Errorf("decode ssh signature: %w", err)
+	}
+
+	return sig, nil
+}
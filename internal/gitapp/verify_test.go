@@ -0,0 +1,296 @@
+package gitapp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+func newSSHSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+func unsignedCommit(message string) *object.Commit {
+	sig := object.Signature{Name: "dev", Email: "dev@example.com", When: time.Unix(1000, 0)}
+
+	return &object.Commit{
+		Author:    sig,
+		Committer: sig,
+		Message:   message,
+		TreeHash:  plumbing.NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904"),
+	}
+}
+
+// sshSign signs c like git with gpg.format=ssh. The signature blob names
+// claimed as its key, a forger can name a trusted key it does not hold.
+func sshSign(t *testing.T, c *object.Commit, signer ssh.Signer, claimed ssh.PublicKey, namespace string) {
+	t.Helper()
+
+	encoded := &plumbing.MemoryObject{}
+	if err := c.EncodeWithoutSignature(encoded); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha512.Sum512(content)
+
+	signed := append([]byte(sshSignatureMagic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          string
+	}{namespace, "", "sha512", string(digest[:])})...)
+	signature, err := signer.Sign(rand.Reader, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var version [4]byte
+	binary.BigEndian.PutUint32(version[:], 1)
+	blob := append([]byte(sshSignatureMagic), version[:]...)
+	blob = append(blob, ssh.Marshal(&sshSignature{
+		PublicKey:     claimed.Marshal(),
+		Namespace:     namespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})...)
+
+	var armored strings.Builder
+	armored.WriteString(sshSignatureArmorStart + "\n")
+	encodedBlob := base64.StdEncoding.EncodeToString(blob)
+	for len(encodedBlob) > 70 {
+		armored.WriteString(encodedBlob[:70] + "\n")
+		encodedBlob = encodedBlob[70:]
+	}
+	armored.WriteString(encodedBlob + "\n" + sshSignatureArmorEnd + "\n")
+
+	c.PGPSignature = armored.String()
+}
+
+func TestVerifySSHSignature(t *testing.T) {
+	trusted := newSSHSigner(t)
+	other := newSSHSigner(t)
+	keys := &trustedKeys{sshKeys: []ssh.PublicKey{trusted.PublicKey()}}
+
+	tests := []struct {
+		name   string
+		commit func() *object.Commit
+		valid  bool
+	}{
+		{
+			name: "signed by a trusted key",
+			commit: func() *object.Commit {
+				c := unsignedCommit("add notes")
+				sshSign(t, c, trusted, trusted.PublicKey(), sshSignatureNamespace)
+				return c
+			},
+			valid: true,
+		},
+		{
+			name: "signed by an untrusted key",
+			commit: func() *object.Commit {
+				c := unsignedCommit("add notes")
+				sshSign(t, c, other, other.PublicKey(), sshSignatureNamespace)
+				return c
+			},
+		},
+		{
+			name: "forged signature naming a trusted key",
+			commit: func() *object.Commit {
+				c := unsignedCommit("add notes")
+				sshSign(t, c, other, trusted.PublicKey(), sshSignatureNamespace)
+				return c
+			},
+		},
+		{
+			name: "commit changed after signing",
+			commit: func() *object.Commit {
+				c := unsignedCommit("add notes")
+				sshSign(t, c, trusted, trusted.PublicKey(), sshSignatureNamespace)
+				c.Message = "add notes and a backdoor"
+				return c
+			},
+		},
+		{
+			name: "signature of another namespace",
+			commit: func() *object.Commit {
+				c := unsignedCommit("add notes")
+				sshSign(t, c, trusted, trusted.PublicKey(), "file")
+				return c
+			},
+		},
+		{
+			name: "garbled signature",
+			commit: func() *object.Commit {
+				c := unsignedCommit("add notes")
+				c.PGPSignature = sshSignatureArmorStart + "\nU1NIU0lH\n" + sshSignatureArmorEnd
+				return c
+			},
+		},
+		{
+			name: "unsigned",
+			commit: func() *object.Commit {
+				return unsignedCommit("add notes")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := keys.verify(tt.commit()); (err == nil) != tt.valid {
+				t.Errorf("verify = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+
+	c := unsignedCommit("add notes")
+	sshSign(t, c, trusted, trusted.PublicKey(), sshSignatureNamespace)
+	if err := (&trustedKeys{gpgKeyRing: "keyring"}).verify(c); err == nil {
+		t.Error("ssh signed commit accepted without trusted ssh keys")
+	}
+}
+
+func TestParseSSHAllowlist(t *testing.T) {
+	a := newSSHSigner(t).PublicKey()
+	b := newSSHSigner(t).PublicKey()
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(a)))
+	allowedSigner := "dev@example.com " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(b)))
+
+	data := strings.Join([]string{"# trusted signers", authorized + " dev", "", "not a key", allowedSigner}, "\n")
+
+	keys := parseSSHAllowlist([]byte(data))
+	if len(keys) != 2 || !bytes.Equal(keys[0].Marshal(), a.Marshal()) || !bytes.Equal(keys[1].Marshal(), b.Marshal()) {
+		t.Errorf("keys = %v", keys)
+	}
+}
+
+func newGPGEntity(t *testing.T, name string) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return entity, buf.String()
+}
+
+// commitSigned commits the worktree like testRepo.commit, signed with key.
+func (r *testRepo) commitSigned(when time.Time, key *openpgp.Entity) plumbing.Hash {
+	r.t.Helper()
+
+	w, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := w.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		r.t.Fatal(err)
+	}
+
+	sig := &object.Signature{Name: "dev", Email: "dev@example.com", When: when}
+	hash, err := w.Commit("commit", &git.CommitOptions{Author: sig, Committer: sig, SignKey: key})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	return hash
+}
+
+func TestVerifyCommitsGPG(t *testing.T) {
+	trusted, keyring := newGPGEntity(t, "release")
+	other, _ := newGPGEntity(t, "intruder")
+
+	keyringFile := filepath.Join(t.TempDir(), "trusted.asc")
+	if err := os.WriteFile(keyringFile, []byte(keyring), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(GitTrustedGPGKeysFileEnv, keyringFile)
+	t.Setenv(GitTrustedSSHKeysFileEnv, "")
+
+	r := newTestRepo(t)
+	r.write("notes/Chart.yaml", "v1")
+	accepted := r.commitSigned(time.Unix(1000, 0), trusted)
+	r.write("notes/Chart.yaml", "v2")
+	unsigned := r.commit(time.Unix(2000, 0))
+	r.write("notes/Chart.yaml", "v3")
+	head := r.commitSigned(time.Unix(3000, 0), trusted)
+	r.write("notes/Chart.yaml", "v4")
+	forged := r.commitSigned(time.Unix(4000, 0), other)
+
+	tests := []struct {
+		name     string
+		mode     string
+		head     plumbing.Hash
+		accepted string
+		valid    bool
+	}{
+		{name: "signed head", mode: VerifyHead, head: head, accepted: accepted.String(), valid: true},
+		{name: "unsigned commit below a signed head", mode: VerifyAll, head: head, accepted: accepted.String()},
+		{name: "nothing after the accepted commit", mode: VerifyAll, head: unsigned, accepted: unsigned.String(), valid: true},
+		{name: "first commit of a fresh install", mode: VerifyAll, head: accepted, valid: true},
+		{name: "unsigned head", mode: VerifyHead, head: unsigned, accepted: accepted.String()},
+		{name: "head signed by an untrusted key", mode: VerifyHead, head: forged, accepted: head.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyCommits(r.repo, tt.mode, tt.head, tt.accepted); (err == nil) != tt.valid {
+				t.Errorf("verifyCommits = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestLoadTrustedKeysWithoutKeys(t *testing.T) {
+	t.Setenv(GitTrustedGPGKeysFileEnv, "")
+	t.Setenv(GitTrustedSSHKeysFileEnv, "")
+
+	if _, err := loadTrustedKeys(); err == nil {
+		t.Error("no error without trusted keys")
+	}
+}
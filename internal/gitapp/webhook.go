@@ -0,0 +1,239 @@
+package gitapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WebhookSecretEnv = "GIT_WEBHOOK_SECRET"
+
+	githubSignatureHeader = "X-Hub-Signature-256"
+	giteaSignatureHeader  = "X-Gitea-Signature"
+	gogsSignatureHeader   = "X-Gogs-Signature"
+	gitlabTokenHeader     = "X-Gitlab-Token"
+
+	branchRefPrefix = "refs/heads/"
+
+	// webhookDeliveryTTL is how long a delivery id is remembered to reject replays
+	webhookDeliveryTTL = 24 * time.Hour
+)
+
+var (
+	ErrWebhookSecretNotSet   = errors.New("webhook secret is not configured")
+	ErrWebhookSignatureEmpty = errors.New("webhook request is not signed")
+	ErrWebhookSignatureWrong = errors.New("webhook signature mismatch")
+	ErrWebhookReplayed       = errors.New("webhook delivery already received")
+)
+
+// deliveryHeaders carry the id the forges give each delivery, GitHub keeps it
+// when a delivery is redelivered.
+var deliveryHeaders = []string{"X-GitHub-Delivery", "X-Gitea-Delivery", "X-Gogs-Delivery", "X-Gitlab-Event-UUID"}
+
+// webhookDeliveries remembers the ids of the verified deliveries.
+var webhookDeliveries = &deliveryLog{seen: make(map[string]time.Time)}
+
+type deliveryLog struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// remember records the delivery id and reports whether it is new.
+func (l *deliveryLog) remember(id string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for seenID, at := range l.seen {
+		if now.Sub(at) > webhookDeliveryTTL {
+			delete(l.seen, seenID)
+		}
+	}
+
+	if _, ok := l.seen[id]; ok {
+		return false
+	}
+	l.seen[id] = now
+
+	return true
+}
+
+// WebhookPush is the subset of a GitHub/Gitea/GitLab push payload the server cares about.
+type WebhookPush struct {
+	Ref    string `json:"ref"`
+	After  string `json:"after"`
+	Before string `json:"before"`
+
+	// Repository is sent by GitHub and Gitea/Gogs, Project by GitLab
+	Repository webhookRepository `json:"repository"`
+	Project    webhookRepository `json:"project"`
+}
+
+type webhookRepository struct {
+	CloneURL   string `json:"clone_url"`
+	SSHURL     string `json:"ssh_url"`
+	HTMLURL    string `json:"html_url"`
+	GitHTTPURL string `json:"git_http_url"`
+	GitSSHURL  string `json:"git_ssh_url"`
+	WebURL     string `json:"web_url"`
+}
+
+func (r *webhookRepository) urls() []string {
+	return []string{r.CloneURL, r.SSHURL, r.HTMLURL, r.GitHTTPURL, r.GitSSHURL, r.WebURL}
+}
+
+func getWebhookSecret() string {
+	return os.Getenv(WebhookSecretEnv)
+}
+
+// VerifyWebhookSignature checks the request body against the configured secret.
+// GitHub and Gitea/Gogs sign the body with HMAC-SHA256, GitLab sends the secret
+// token as is. A verified delivery whose id was already received is a replay.
+func VerifyWebhookSignature(header http.Header, body []byte) error {
+	if err := verifyWebhookSignature(header, body); err != nil {
+		return err
+	}
+
+	for _, name := range deliveryHeaders {
+		if id := header.Get(name); id != "" {
+			if !webhookDeliveries.remember(name+":"+id, time.Now()) {
+				return ErrWebhookReplayed
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func verifyWebhookSignature(header http.Header, body []byte) error {
+	secret := getWebhookSecret()
+	if secret == "" {
+		return ErrWebhookSecretNotSet
+	}
+
+	if sig := header.Get(githubSignatureHeader); sig != "" {
+		return verifyHmacSHA256(secret, strings.TrimPrefix(sig, "sha256="), body)
+	}
+
+	if sig := header.Get(giteaSignatureHeader); sig != "" {
+		return verifyHmacSHA256(secret, sig, body)
+	}
+
+	if sig := header.Get(gogsSignatureHeader); sig != "" {
+		return verifyHmacSHA256(secret, sig, body)
+	}
+
+	if token := header.Get(gitlabTokenHeader); token != "" {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return ErrWebhookSignatureWrong
+		}
+		return nil
+	}
+
+	return ErrWebhookSignatureEmpty
+}
+
+func verifyHmacSHA256(secret, signature string, body []byte) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrWebhookSignatureWrong
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrWebhookSignatureWrong
+	}
+
+	return nil
+}
+
+func ParseWebhookPush(body []byte) (*WebhookPush, error) {
+	push := &WebhookPush{}
+	if err := json.Unmarshal(body, push); err != nil {
+		return nil, fmt.Errorf("invalid push payload: %w", err)
+	}
+
+	return push, nil
+}
+
+// Branch returns the branch name of the push, or "" for tag pushes and other refs.
+func (p *WebhookPush) Branch() string {
+	if !strings.HasPrefix(p.Ref, branchRefPrefix) {
+		return ""
+	}
+
+	return strings.TrimPrefix(p.Ref, branchRefPrefix)
+}
+
+// TrackedSource returns the source the push belongs to, or nil when the push
+// does not target a tracked branch. Payloads without repository urls are
+// matched by branch only.
+func (p *WebhookPush) TrackedSource() *Source {
+	branch := p.Branch()
+	if branch == "" {
+		return nil
+	}
+
+	var urls []string
+	for _, u := range append(p.Repository.urls(), p.Project.urls()...) {
+		if u != "" {
+			urls = append(urls, normalizeRepoURL(u))
+		}
+	}
+
+	for _, s := range sources {
+		if s.isLocal() || s.Branch != branch {
+			continue
+		}
+		if len(urls) == 0 {
+			return s
+		}
+		for _, u := range urls {
+			if u == normalizeRepoURL(s.URL) {
+				return s
+			}
+		}
+	}
+
+	return nil
+}
+
+// IsTrackedBranch reports whether the push targets a branch the catalog follows.
+func (p *WebhookPush) IsTrackedBranch() bool {
+	return p.TrackedSource() != nil
+}
+
+// normalizeRepoURL reduces the https, ssh and scp-like forms of a repository
+// url to host/path, e.g. git@github.com:Above-Os/apps.git -> github.com/above-os/apps
+func normalizeRepoURL(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if i := strings.Index(u, ":"); i >= 0 {
+		u = u[:i] + "/" + u[i+1:]
+	}
+	if i := strings.Index(u, "@"); i >= 0 && i < strings.Index(u+"/", "/") {
+		u = u[i+1:]
+	}
+	if i := strings.Index(u, "/"); i >= 0 {
+		// drop the port of ssh://git@host:22/path
+		if j := strings.Index(u[:i], ":"); j >= 0 {
+			u = u[:j] + u[i:]
+		}
+	}
+	u = strings.TrimSuffix(u, "/")
+	u = strings.TrimSuffix(u, ".git")
+
+	return u
+}
@@ -0,0 +1,213 @@
+package gitapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "s3cret"
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func header(pairs ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i < len(pairs); i += 2 {
+		h.Set(pairs[i], pairs[i+1])
+	}
+
+	return h
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	tampered := []byte(`{"ref":"refs/heads/evil"}`)
+
+	tests := []struct {
+		name   string
+		secret string
+		header http.Header
+		body   []byte
+		err    error
+	}{
+		{
+			name:   "github",
+			secret: testWebhookSecret,
+			header: header("X-Hub-Signature-256", "sha256="+sign(testWebhookSecret, body)),
+			body:   body,
+		},
+		{
+			name:   "gitea",
+			secret: testWebhookSecret,
+			header: header("X-Gitea-Signature", sign(testWebhookSecret, body)),
+			body:   body,
+		},
+		{
+			name:   "gogs",
+			secret: testWebhookSecret,
+			header: header("X-Gogs-Signature", sign(testWebhookSecret, body)),
+			body:   body,
+		},
+		{
+			name:   "gitlab",
+			secret: testWebhookSecret,
+			header: header("X-Gitlab-Token", testWebhookSecret),
+			body:   body,
+		},
+		{
+			name:   "tampered body",
+			secret: testWebhookSecret,
+			header: header("X-Hub-Signature-256", "sha256="+sign(testWebhookSecret, body)),
+			body:   tampered,
+			err:    ErrWebhookSignatureWrong,
+		},
+		{
+			name:   "signed with another secret",
+			secret: testWebhookSecret,
+			header: header("X-Gitea-Signature", sign("other", body)),
+			body:   body,
+			err:    ErrWebhookSignatureWrong,
+		},
+		{
+			name:   "signature that is not hex",
+			secret: testWebhookSecret,
+			header: header("X-Hub-Signature-256", "sha256=zz"),
+			body:   body,
+			err:    ErrWebhookSignatureWrong,
+		},
+		{
+			name:   "wrong gitlab token",
+			secret: testWebhookSecret,
+			header: header("X-Gitlab-Token", "guess"),
+			body:   body,
+			err:    ErrWebhookSignatureWrong,
+		},
+		{
+			name:   "unsigned",
+			secret: testWebhookSecret,
+			header: header(),
+			body:   body,
+			err:    ErrWebhookSignatureEmpty,
+		},
+		{
+			name:   "no secret configured",
+			header: header("X-Gitlab-Token", ""),
+			body:   body,
+			err:    ErrWebhookSecretNotSet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(WebhookSecretEnv, tt.secret)
+
+			if err := VerifyWebhookSignature(tt.header, tt.body); !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookSignatureReplay(t *testing.T) {
+	t.Setenv(WebhookSecretEnv, testWebhookSecret)
+	webhookDeliveries = &deliveryLog{seen: make(map[string]time.Time)}
+
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	signed := func(delivery string) http.Header {
+		return header("X-Hub-Signature-256", "sha256="+sign(testWebhookSecret, body), "X-GitHub-Delivery", delivery)
+	}
+
+	if err := VerifyWebhookSignature(signed("d1"), body); err != nil {
+		t.Fatalf("first delivery: %v", err)
+	}
+	if err := VerifyWebhookSignature(signed("d1"), body); !errors.Is(err, ErrWebhookReplayed) {
+		t.Errorf("replayed delivery err = %v, want %v", err, ErrWebhookReplayed)
+	}
+	if err := VerifyWebhookSignature(signed("d2"), body); err != nil {
+		t.Errorf("next delivery: %v", err)
+	}
+
+	// a forged delivery is rejected before its id is remembered
+	forged := header("X-Hub-Signature-256", "sha256="+sign("other", body), "X-GitHub-Delivery", "d3")
+	if err := VerifyWebhookSignature(forged, body); !errors.Is(err, ErrWebhookSignatureWrong) {
+		t.Errorf("forged delivery err = %v, want %v", err, ErrWebhookSignatureWrong)
+	}
+	if err := VerifyWebhookSignature(signed("d3"), body); err != nil {
+		t.Errorf("delivery after a forged one with its id: %v", err)
+	}
+}
+
+func TestDeliveryLogExpires(t *testing.T) {
+	l := &deliveryLog{seen: make(map[string]time.Time)}
+	now := time.Unix(1000, 0)
+
+	if !l.remember("d1", now) {
+		t.Fatal("first delivery is not new")
+	}
+	if l.remember("d1", now.Add(time.Hour)) {
+		t.Error("delivery remembered for an hour is new")
+	}
+	if !l.remember("d1", now.Add(webhookDeliveryTTL+time.Second)) {
+		t.Error("expired delivery is not new")
+	}
+}
+
+func TestTrackedSource(t *testing.T) {
+	saved := sources
+	t.Cleanup(func() { sources = saved })
+
+	main := &Source{Name: "main", Type: SourceTypeGit, URL: "https://github.com/Above-Os/apps.git", Branch: "main"}
+	extra := &Source{Name: "extra", Type: SourceTypeGit, URL: "git@gitea.example.com:team/extra.git", Branch: "release"}
+	local := &Source{Name: "local", Type: SourceTypeLocal, Path: "/srv/apps", Branch: "main"}
+	sources = []*Source{local, main, extra}
+
+	tests := []struct {
+		name string
+		push WebhookPush
+		want *Source
+	}{
+		{
+			name: "github push by clone url",
+			push: WebhookPush{Ref: "refs/heads/main", Repository: webhookRepository{CloneURL: "https://github.com/above-os/apps"}},
+			want: main,
+		},
+		{
+			name: "gitlab push by ssh url",
+			push: WebhookPush{Ref: "refs/heads/release", Project: webhookRepository{GitSSHURL: "ssh://git@gitea.example.com:22/team/extra.git"}},
+			want: extra,
+		},
+		{
+			name: "payload without urls matches the branch",
+			push: WebhookPush{Ref: "refs/heads/release"},
+			want: extra,
+		},
+		{
+			name: "other repository on a tracked branch",
+			push: WebhookPush{Ref: "refs/heads/main", Repository: webhookRepository{CloneURL: "https://github.com/someone/apps.git"}},
+		},
+		{
+			name: "untracked branch",
+			push: WebhookPush{Ref: "refs/heads/dev", Repository: webhookRepository{CloneURL: "https://github.com/above-os/apps.git"}},
+		},
+		{
+			name: "tag push",
+			push: WebhookPush{Ref: "refs/tags/main"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.push.TrackedSource(); got != tt.want {
+				t.Errorf("source = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package i18n
+
+import (
+	"app-store-server/pkg/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header,
+// most preferred first. The wildcard and languages with q=0 are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var list []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+				q = v
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		list = append(list, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+
+	tags := make([]string, 0, len(list))
+	for _, w := range list {
+		tags = append(tags, w.tag)
+	}
+
+	return tags
+}
+
+// primary returns the language subtag of a tag, "zh" for "zh-TW"
+func primary(tag string) string {
+	tag = strings.ReplaceAll(tag, "_", "-")
+	if i := strings.Index(tag, "-"); i >= 0 {
+		return tag[:i]
+	}
+
+	return tag
+}
+
+func sameTag(a, b string) bool {
+	return strings.EqualFold(strings.ReplaceAll(a, "_", "-"), strings.ReplaceAll(b, "_", "-"))
+}
+
+// Chain returns the locales of available to resolve the requested languages
+// with, in order. Each requested language falls back to the other locales of
+// the same language, zh-TW to zh-CN, before the next requested language. The
+// base fields of the app come after the chain.
+func Chain(requested []string, available []string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(locale string) {
+		if !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+
+	for _, tag := range requested {
+		for _, locale := range available {
+			if sameTag(tag, locale) {
+				add(locale)
+			}
+		}
+		for _, locale := range available {
+			if strings.EqualFold(primary(tag), primary(locale)) {
+				add(locale)
+			}
+		}
+	}
+
+	return chain
+}
+
+// Localize resolves the translatable fields of an app and its variants with
+// the requested languages, each field from the first locale of the chain
+// translating it. The I18n map is dropped when omitMap is set.
+func Localize(entry *models.ApplicationInfoEntry, requested []string, omitMap bool) {
+	translations := entry.I18n
+	if len(requested) > 0 && len(translations) > 0 {
+		available := make([]string, 0, len(translations))
+		for locale := range translations {
+			available = append(available, locale)
+		}
+		// keep the fallback between locales of one language stable
+		sort.Strings(available)
+
+		chain := Chain(requested, available)
+		if len(chain) > 0 {
+			localize(entry, translations, chain)
+			for name, v := range entry.Variants {
+				localize(&v, translations, chain)
+				entry.Variants[name] = v
+			}
+		}
+	}
+
+	if omitMap {
+		entry.I18n = nil
+	}
+}
+
+func localize(entry *models.ApplicationInfoEntry, translations map[string]models.I18n, chain []string) {
+	resolve := func(base string, field func(t models.I18n) string) string {
+		for _, locale := range chain {
+			if v := field(translations[locale]); v != "" {
+				return v
+			}
+		}
+		return base
+	}
+
+	entry.Title = resolve(entry.Title, func(t models.I18n) string { return t.Metadata.Title })
+	entry.Description = resolve(entry.Description, func(t models.I18n) string { return t.Metadata.Description })
+	entry.FullDescription = resolve(entry.FullDescription, func(t models.I18n) string { return t.Spec.FullDescription })
+	entry.UpgradeDescription = resolve(entry.UpgradeDescription, func(t models.I18n) string { return t.Spec.UpgradeDescription })
+
+	if len(entry.Entrances) == 0 {
+		return
+	}
+	// the entrances are shared with the stored app, localize a copy
+	entrances := make([]models.Entrance, len(entry.Entrances))
+	copy(entrances, entry.Entrances)
+	for i := range entrances {
+		name := entrances[i].Name
+		entrances[i].Title = resolve(entrances[i].Title, func(t models.I18n) string {
+			for _, e := range t.Entrances {
+				if e.Name == name {
+					return e.Title
+				}
+			}
+			return ""
+		})
+	}
+	entry.Entrances = entrances
+}
@@ -0,0 +1,123 @@
+package i18n
+
+import (
+	"app-store-server/pkg/models"
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{header: "", want: []string{}},
+		{header: "zh-CN", want: []string{"zh-CN"}},
+		{header: "en;q=0.5, zh-TW, ja;q=0.8", want: []string{"zh-TW", "ja", "en"}},
+		// equal weights keep the order of the header
+		{header: "fr;q=0.7, de;q=0.7, en", want: []string{"en", "fr", "de"}},
+		{header: "en, *;q=0.5, de;q=0", want: []string{"en"}},
+		{header: "en;q=bad, fr;q=0.9", want: []string{"en", "fr"}},
+		{header: " en-US ; q=0.3 ,, zh ", want: []string{"zh", "en-US"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	available := []string{"en-US", "zh-CN", "zh-TW", "ja_JP"}
+
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+	}{
+		{name: "exact match first", requested: []string{"zh-TW"}, want: []string{"zh-TW", "zh-CN"}},
+		{name: "same language falls back", requested: []string{"zh-HK"}, want: []string{"zh-CN", "zh-TW"}},
+		{name: "bare language", requested: []string{"zh"}, want: []string{"zh-CN", "zh-TW"}},
+		{name: "case and separator", requested: []string{"ja-jp"}, want: []string{"ja_JP"}},
+		{name: "language fallback before the next request", requested: []string{"zh-TW", "en"}, want: []string{"zh-TW", "zh-CN", "en-US"}},
+		{name: "unknown languages leave the base", requested: []string{"fr", "de"}, want: nil},
+		{name: "no request", requested: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Chain(tt.requested, available); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain(%v) = %v, want %v", tt.requested, got, tt.want)
+			}
+		})
+	}
+}
+
+func app() *models.ApplicationInfoEntry {
+	return &models.ApplicationInfoEntry{
+		Title:       "Notes",
+		Description: "Take notes",
+		Entrances:   []models.Entrance{{Name: "web", Title: "Notes"}},
+		I18n: map[string]models.I18n{
+			"zh-CN": {
+				Metadata:  models.I18nMetadata{Title: "笔记", Description: "记笔记"},
+				Entrances: []models.I18nEntrance{{Name: "web", Title: "笔记"}},
+			},
+			// only the title is translated, the rest falls back to zh-CN
+			"zh-TW": {Metadata: models.I18nMetadata{Title: "筆記"}},
+		},
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	tests := []struct {
+		name        string
+		requested   []string
+		title       string
+		description string
+		entrance    string
+	}{
+		{name: "zh-TW falls back to zh-CN", requested: []string{"zh-TW"}, title: "筆記", description: "记笔记", entrance: "笔记"},
+		{name: "zh-CN", requested: []string{"zh-CN"}, title: "笔记", description: "记笔记", entrance: "笔记"},
+		{name: "unknown language keeps the base", requested: []string{"fr"}, title: "Notes", description: "Take notes", entrance: "Notes"},
+		{name: "no request keeps the base", requested: nil, title: "Notes", description: "Take notes", entrance: "Notes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := app()
+			stored := entry.Entrances
+			Localize(entry, tt.requested, false)
+
+			if entry.Title != tt.title || entry.Description != tt.description || entry.Entrances[0].Title != tt.entrance {
+				t.Errorf("got %q %q %q, want %q %q %q", entry.Title, entry.Description, entry.Entrances[0].Title,
+					tt.title, tt.description, tt.entrance)
+			}
+			if stored[0].Title != "Notes" {
+				t.Errorf("stored entrances localized")
+			}
+			if entry.I18n == nil {
+				t.Errorf("i18n map dropped")
+			}
+		})
+	}
+}
+
+func TestLocalizeVariantsAndOmitMap(t *testing.T) {
+	entry := app()
+	variant := app()
+	variant.Title = "Notes GPU"
+	entry.Variants = map[string]models.ApplicationInfoEntry{"gpu": *variant}
+
+	Localize(entry, []string{"zh-CN"}, true)
+
+	if entry.I18n != nil {
+		t.Errorf("i18n map kept")
+	}
+	if got := entry.Variants["gpu"].Title; got != "笔记" {
+		t.Errorf("variant title = %q", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -57,7 +58,7 @@ func getCacheDir() string {
 
 func DownloadImagesInfo(chartDir string) error {
 	// 1. Extract all images from chart directory
-	images, err := extractImagesFromDirectory(chartDir)
+	images, err := extractChartImages(chartDir)
 	if err != nil {
 		return fmt.Errorf("failed to extract images: %w", err)
 	}
@@ -492,7 +493,19 @@ func extractRegistryAndRepository(imageName string) (string, string) {
 	}
 }
 
-// extractImagesFromDirectory extracts all Docker image references from rendered chart files
+// ExtractImages returns the image references of a chart directory, sorted. It
+// needs no network, the manifests are not downloaded.
+func ExtractImages(chartDir string) ([]string, error) {
+	images, err := extractChartImages(chartDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(images)
+
+	return images, nil
+}
+
+// extractImagesFromDirectory extracts the Docker image references of the raw chart files
 func extractImagesFromDirectory(chartDir string) ([]string, error) {
 	imageSet := make(map[string]bool)
 
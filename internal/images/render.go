@@ -0,0 +1,153 @@
+package images
+
+import (
+	"app-store-server/internal/variant"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+// podSpecContainerKeys are the container lists of a pod spec
+var podSpecContainerKeys = []string{"containers", "initContainers", "ephemeralContainers"}
+
+// extractChartImages returns the images of a chart, found in its templates
+// rendered with the default values and each render variant. When the chart
+// does not render the raw YAML files are searched instead.
+func extractChartImages(chartDir string) ([]string, error) {
+	images, err := extractImagesFromRenderedChart(chartDir)
+	if err == nil {
+		return images, nil
+	}
+
+	log.Printf("Warning: failed to render chart %s, searching the raw files for images: %v", chartDir, err)
+	return extractImagesFromDirectory(chartDir)
+}
+
+// extractImagesFromRenderedChart renders the chart once per render variant,
+// the chart values overlaid with the values of the variant, and collects the
+// images of every pod spec. It fails only when no variant renders.
+func extractImagesFromRenderedChart(chartDir string) ([]string, error) {
+	chrt, err := loader.Load(chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("load chart: %w", err)
+	}
+
+	options := chartutil.ReleaseOptions{
+		Name:      chrt.Name(),
+		Namespace: chrt.Name(),
+		IsInstall: true,
+	}
+
+	imageSet := make(map[string]bool)
+	var errs []error
+	contexts := variant.Contexts()
+	for _, ctx := range contexts {
+		values, err := chartutil.ToRenderValues(chrt, ctx.Values, options, chartutil.DefaultCapabilities)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("variant %s: %w", ctx.Name, err))
+			continue
+		}
+
+		// lint mode keeps a missing required value from failing the whole chart
+		manifests, err := engine.Engine{LintMode: true}.Render(chrt, values)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("variant %s: %w", ctx.Name, err))
+			continue
+		}
+
+		for name, manifest := range manifests {
+			if !isYAMLFile(name) {
+				continue
+			}
+
+			images, err := extractImagesFromManifest(manifest)
+			if err != nil {
+				log.Printf("Warning: failed to parse rendered %s: %v", name, err)
+				continue
+			}
+			for _, image := range images {
+				imageSet[image] = true
+			}
+		}
+	}
+
+	if len(errs) == len(contexts) {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		log.Printf("Warning: chart %s: %v", chartDir, err)
+	}
+
+	images := make([]string, 0, len(imageSet))
+	for image := range imageSet {
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// extractImagesFromManifest returns the images of the pod specs in a rendered
+// multi-document manifest.
+func extractImagesFromManifest(manifest string) ([]string, error) {
+	var images []string
+
+	decoder := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		collectContainerImages(doc, &images)
+	}
+
+	return images, nil
+}
+
+// collectContainerImages walks a decoded object and collects the images of
+// the container lists it finds, whatever the kind of the workload holding the
+// pod spec.
+func collectContainerImages(node interface{}, images *[]string) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for _, key := range podSpecContainerKeys {
+			containers, ok := n[key].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				image, ok := container["image"].(string)
+				if !ok {
+					continue
+				}
+				image = cleanImageName(image)
+				if image != "" && isValidImageName(image) {
+					*images = append(*images, image)
+				}
+			}
+		}
+
+		for _, v := range n {
+			collectContainerImages(v, images)
+		}
+	case []interface{}:
+		for _, v := range n {
+			collectContainerImages(v, images)
+		}
+	}
+}
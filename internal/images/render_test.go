@@ -0,0 +1,167 @@
+package images
+
+import (
+	"app-store-server/internal/variant"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeChart writes the files of a chart into a temporary directory.
+func writeChart(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// setRenderVariants configures the render variants for the test.
+func setRenderVariants(t *testing.T, config string) {
+	t.Helper()
+
+	t.Setenv(variant.RenderVariantsEnv, config)
+	if err := variant.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv(variant.RenderVariantsEnv)
+		_ = variant.Init()
+	})
+}
+
+const chartYAML = `apiVersion: v2
+name: notes
+version: 1.0.0
+`
+
+func TestExtractImagesRendersTemplates(t *testing.T) {
+	setRenderVariants(t, `[
+		{"name":"cpu","values":{"GPU":{"Type":"none"}}},
+		{"name":"nvidia","values":{"GPU":{"Type":"nvidia"}}}
+	]`)
+
+	chartDir := writeChart(t, map[string]string{
+		"Chart.yaml": chartYAML,
+		"values.yaml": `image:
+  repository: docker.io/beclab/notes
+  tag: "1.0.0"
+`,
+		"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: "busybox:1.36"
+      containers:
+      - name: notes
+        image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+      {{- if eq .Values.GPU.Type "nvidia" }}
+      - name: worker
+        image: beclab/notes-cuda:1.0.0
+      {{- end }}
+`,
+		"templates/cronjob.yaml": `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cleanup
+  labels:
+    image: not-an-image
+spec:
+  schedule: "@daily"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: cleanup
+            image: ghcr.io/beclab/cleanup@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+`,
+		"templates/NOTES.txt": `image: ignored:1.0`,
+	})
+
+	images, err := ExtractImages(chartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"beclab/notes-cuda:1.0.0",
+		"beclab/notes:1.0.0",
+		"busybox:1.36",
+		"ghcr.io/beclab/cleanup@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+}
+
+func TestExtractImagesFallsBackToRawFiles(t *testing.T) {
+	setRenderVariants(t, "")
+
+	chartDir := writeChart(t, map[string]string{
+		"Chart.yaml": chartYAML,
+		"templates/deployment.yaml": `spec:
+  containers:
+  - name: notes
+    image: beclab/notes:1.0.0
+  - name: broken
+    image: {{ .Values.image.repository
+`,
+	})
+
+	images, err := ExtractImages(chartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"beclab/notes:1.0.0"}; !reflect.DeepEqual(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+}
+
+func TestExtractImagesFromManifest(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - name: app
+    image: nginx:1.25
+  ephemeralContainers:
+  - name: debug
+    image: docker.io/library/busybox:1.36
+---
+apiVersion: v1
+kind: ConfigMap
+data:
+  image: "not/a-container:1.0"
+---
+`
+
+	images, err := extractImagesFromManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(images)
+	if want := []string{"library/busybox:1.36", "nginx:1.25"}; !reflect.DeepEqual(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+
+	if _, err := extractImagesFromManifest("spec: [unclosed"); err == nil {
+		t.Error("no error for a manifest that does not parse")
+	}
+}
@@ -0,0 +1,187 @@
+package leader
+
+import (
+	"app-store-server/internal/mongo"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// LeaderElectionEnv set to "true" elects one replica to sync the catalog and
+	// write Mongo and ES, the others only serve reads. Without it every replica
+	// is the leader, as a single replica always was.
+	LeaderElectionEnv = "LEADER_ELECTION"
+	// LeaseDurationEnv is how long the lease lasts without renewal, a Go duration like "30s"
+	LeaseDurationEnv = "LEADER_LEASE_DURATION"
+	// PodNameEnv names the replica in the lease, the hostname is used without it
+	PodNameEnv = "POD_NAME"
+
+	DefaultLeaseDuration = 30 * time.Second
+
+	leaseName = "app-store-server"
+)
+
+// ErrNotLeader is returned by work that stopped because the replica no longer
+// leads in the term it started in.
+var ErrNotLeader = errors.New("replica is not the leader")
+
+var (
+	enabled  bool
+	identity string
+	isLeader int32
+	// term counts the leaderships of the replica
+	term uint64
+
+	callbacksMu      sync.Mutex
+	onStartedLeading []func()
+)
+
+func getLeaseDuration() time.Duration {
+	d, err := time.ParseDuration(os.Getenv(LeaseDurationEnv))
+	if err == nil && d > 0 {
+		return d
+	}
+
+	return DefaultLeaseDuration
+}
+
+func getIdentity() string {
+	name := os.Getenv(PodNameEnv)
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+
+	// a restarted replica must not resume the lease of its previous process
+	return fmt.Sprintf("%s-%s", name, primitive.NewObjectID().Hex()[16:])
+}
+
+// Init tries to take the lease once, so the other packages know the role of
+// the replica when they start, and keeps competing for it in the background.
+func Init() error {
+	enabled = strings.EqualFold(os.Getenv(LeaderElectionEnv), "true")
+	identity = getIdentity()
+
+	if !enabled {
+		atomic.StoreUint64(&term, 1)
+		atomic.StoreInt32(&isLeader, 1)
+		return nil
+	}
+
+	ttl := getLeaseDuration()
+	start := time.Now()
+	acquired, err := mongo.AcquireLease(leaseName, identity, ttl)
+	if err != nil {
+		return err
+	}
+	setLeader(acquired)
+	glog.Infof("replica %s started, leader:%v", identity, acquired)
+
+	go renewLoop(ttl, start.Add(ttl))
+
+	return nil
+}
+
+// renewLoop renews the lease, expireAt is when the last renewal runs out.
+func renewLoop(ttl time.Duration, expireAt time.Time) {
+	interval := ttl / 3
+	for {
+		time.Sleep(interval)
+
+		start := time.Now()
+		acquired, err := mongo.AcquireLease(leaseName, identity, ttl)
+		if err != nil {
+			glog.Warningf("acquire lease err:%s", err.Error())
+			// nobody else can take the lease before it expires, keep it
+			// while the next renewal may still come in time
+			acquired = IsLeader() && time.Now().Add(interval).Before(expireAt)
+		} else if acquired {
+			expireAt = start.Add(ttl)
+		}
+		setLeader(acquired)
+	}
+}
+
+// setLeader is only called by Init and renewLoop, never concurrently. The term
+// moves before a leadership starts and after it ends, so work of the old term
+// never sees itself leading in the new one.
+func setLeader(leader bool) {
+	old := IsLeader()
+	switch {
+	case !old && leader:
+		atomic.AddUint64(&term, 1)
+		atomic.StoreInt32(&isLeader, 1)
+		glog.Infof("replica %s became the leader", identity)
+		callbacksMu.Lock()
+		callbacks := append([]func(){}, onStartedLeading...)
+		callbacksMu.Unlock()
+		for _, fn := range callbacks {
+			go fn()
+		}
+	case old && !leader:
+		atomic.StoreInt32(&isLeader, 0)
+		atomic.AddUint64(&term, 1)
+		glog.Warningf("replica %s lost the leadership", identity)
+	}
+}
+
+// IsLeader reports whether this replica may sync the catalog and write Mongo and ES.
+func IsLeader() bool {
+	return atomic.LoadInt32(&isLeader) == 1
+}
+
+// Term returns the current leadership of the replica, it changes each time
+// the replica becomes the leader again.
+func Term() uint64 {
+	return atomic.LoadUint64(&term)
+}
+
+// Leading reports whether the replica still leads in the term work started
+// in. Work of an earlier term must stop writing, another replica may have led
+// in between.
+func Leading(t uint64) bool {
+	return IsLeader() && Term() == t
+}
+
+// Enabled reports whether leader election is on.
+func Enabled() bool {
+	return enabled
+}
+
+// Identity returns the name of this replica in the lease.
+func Identity() string {
+	return identity
+}
+
+// OnStartedLeading registers fn to run each time the replica becomes the
+// leader, and right away when it already is.
+func OnStartedLeading(fn func()) {
+	callbacksMu.Lock()
+	onStartedLeading = append(onStartedLeading, fn)
+	callbacksMu.Unlock()
+
+	if IsLeader() {
+		go fn()
+	}
+}
+
+// GetLeader returns the replica holding the lease, "" when nobody does.
+func GetLeader() (string, error) {
+	if !enabled {
+		return identity, nil
+	}
+
+	lease, err := mongo.GetLease(leaseName)
+	if err != nil || lease == nil || lease.ExpireAt.Before(time.Now()) {
+		return "", err
+	}
+
+	return lease.Holder, nil
+}
@@ -16,8 +16,41 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// GetAppLists returns the apps of the catalog, without the apps of hidden categories.
 func GetAppLists(offset, size int64, category, ty string) (list []*models.ApplicationInfoFullData, count int64, err error) {
-	filter := make(bson.M)
+	return GetAppListsWithLabels(offset, size, category, ty, nil, nil, nil)
+}
+
+// GetAppListsWithLabels is GetAppLists limited to the apps having all of
+// labels and none of excludedLabels, and requesting all of the middleware kinds.
+func GetAppListsWithLabels(offset, size int64, category, ty string, labels, excludedLabels, middleware []string) (list []*models.ApplicationInfoFullData, count int64, err error) {
+	filter := bson.M{"removedAt": bson.M{"$exists": false}}
+	err = addHiddenCategoriesFilter(filter, excludedLabels)
+	if err != nil {
+		return
+	}
+	addMiddlewareFilter(filter, middleware)
+
+	return getAppLists(offset, size, category, ty, labels, excludedLabels, filter)
+}
+
+// GetAllAppLists returns the apps of the catalog whatever the category
+// policies, the search index holds them all and filters at query time.
+func GetAllAppLists(offset, size int64) (list []*models.ApplicationInfoFullData, count int64, err error) {
+	return getAppLists(offset, size, "", "", nil, nil, bson.M{"removedAt": bson.M{"$exists": false}})
+}
+
+func getAppLists(offset, size int64, category, ty string, labels, excludedLabels []string, filter bson.M) (list []*models.ApplicationInfoFullData, count int64, err error) {
+	appLabels := bson.M{}
+	if len(labels) > 0 {
+		appLabels["$all"] = labels
+	}
+	if len(excludedLabels) > 0 {
+		appLabels["$nin"] = excludedLabels
+	}
+	if len(appLabels) > 0 {
+		filter["appLabels"] = appLabels
+	}
 	if category != "" {
 		//filter["categories"] = category
 		//regex := primitive.Regex{Pattern: category, Options: "i"}
@@ -100,7 +133,7 @@ func GetAppLists(offset, size int64, category, ty string) (list []*models.Applic
 }
 
 func GetAppInfos(names []string) (mapInfo map[string]*models.ApplicationInfoFullData, err error) {
-	filter := make(bson.M)
+	filter := bson.M{"removedAt": bson.M{"$exists": false}}
 	if len(names) > 0 {
 		filter["name"] = bson.M{"$in": names}
 	}
@@ -138,10 +171,14 @@ func GetAppInfos(names []string) (mapInfo map[string]*models.ApplicationInfoFull
 	return
 }
 
+// GetAppInfoByName returns a published app, nil when there is none or it was removed.
 func GetAppInfoByName(name string) (*models.ApplicationInfoFullData, error) {
-	filter := bson.M{"name": name}
+	filter := bson.M{"name": name, "removedAt": bson.M{"$exists": false}}
 	info := &models.ApplicationInfoFullData{}
 	err := mgoClient.queryOne(AppStoreDb, AppInfosCollection, filter).Decode(&info)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
 	if err != nil {
 		glog.Warningf("err:%s", err.Error())
 		return nil, err
@@ -158,6 +195,157 @@ func DisableAppInfoToDb(appInfo *models.ApplicationInfoFullData) error {
 	return err
 }
 
+// CarryForwardAppInfos moves the apps of oldHash to newHash without touching
+// their content, so apps untouched by a pull stay visible. Apps whose names are
+// in excludeNames are left behind.
+func CarryForwardAppInfos(oldHash, newHash string, excludeNames []string) error {
+	filter := bson.M{"history.latest.lastCommitHash": oldHash}
+	if len(excludeNames) > 0 {
+		filter["name"] = bson.M{"$nin": excludeNames}
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"history.latest.lastCommitHash": newHash,
+		},
+	}
+
+	res, err := mgoClient.updateMany(AppStoreDb, AppInfosCollection, filter, update)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return err
+	}
+	glog.Infof("carry forward %d apps from %s to %s", res.ModifiedCount, oldHash, newHash)
+
+	return nil
+}
+
+// GetListedAppInfos returns the apps that are not removed, whatever commit
+// they were ingested at.
+func GetListedAppInfos() ([]*models.ApplicationInfoFullData, error) {
+	return findAppInfos(bson.M{"removedAt": bson.M{"$exists": false}})
+}
+
+// TombstoneAppInfos marks the apps whose names are not in presentNames as
+// removed at removedAt and returns them. Apps already marked keep their time.
+func TombstoneAppInfos(presentNames []string, removedAt int64) (list []*models.ApplicationInfoFullData, err error) {
+	filter := bson.M{
+		"name":      bson.M{"$nin": presentNames},
+		"removedAt": bson.M{"$exists": false},
+	}
+
+	list, err = findAppInfos(filter)
+	if err != nil || len(list) == 0 {
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"removedAt": removedAt,
+		},
+	}
+	res, err := mgoClient.updateMany(AppStoreDb, AppInfosCollection, filter, update)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return nil, err
+	}
+	glog.Infof("tombstone %d apps", res.ModifiedCount)
+
+	return
+}
+
+// PurgeAppInfos deletes the apps removed at or before removedBefore and returns them.
+func PurgeAppInfos(removedBefore int64) (list []*models.ApplicationInfoFullData, err error) {
+	filter := bson.M{"removedAt": bson.M{"$lte": removedBefore}}
+
+	list, err = findAppInfos(filter)
+	if err != nil || len(list) == 0 {
+		return
+	}
+
+	res, err := mgoClient.deleteMany(AppStoreDb, AppInfosCollection, filter)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return nil, err
+	}
+	glog.Infof("purge %d removed apps", res.DeletedCount)
+
+	return
+}
+
+// GetChartNames returns the charts referenced by any version of any app, removed
+// apps included.
+func GetChartNames() (map[string]bool, error) {
+	list, err := findAppInfos(bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, info := range list {
+		for _, entry := range info.History {
+			if entry.ChartName != "" {
+				names[entry.ChartName] = true
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// GetAppInfosWithLabels returns the apps whose latest version declares labels.
+func GetAppInfosWithLabels() ([]*models.ApplicationInfoFullData, error) {
+	return findAppInfos(bson.M{
+		"history.latest.labels.0": bson.M{"$exists": true},
+		"removedAt":               bson.M{"$exists": false},
+	})
+}
+
+// SetAppLabels replaces the AppLabels of an app and of its latest version and
+// returns the updated app.
+func SetAppLabels(name, version string, labels []string) (*models.ApplicationInfoFullData, error) {
+	versionKey := strings.Replace(version, ".", "_", -1)
+	update := bson.M{
+		"$set": bson.M{
+			"appLabels":                labels,
+			"history.latest.appLabels": labels,
+			fmt.Sprintf("history.%s.appLabels", versionKey): labels,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	updated := &models.ApplicationInfoFullData{}
+	err := mgoClient.findOneAndUpdate(AppStoreDb, AppInfosCollection, bson.M{"name": name}, update, opts).Decode(updated)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func findAppInfos(filter bson.M) (list []*models.ApplicationInfoFullData, err error) {
+	cur, err := mgoClient.queryMany(AppStoreDb, AppInfosCollection, filter)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		result := &models.ApplicationInfoFullData{}
+		err := cur.Decode(result)
+		if err != nil {
+			glog.Warningf("err:%s", err.Error())
+			continue
+		}
+		list = append(list, result)
+	}
+
+	return list, cur.Err()
+}
+
 func UpsertAppInfoToDb(appInfo *models.ApplicationInfoFullData) error {
 	filter := bson.M{"name": appInfo.Name}
 	updatedDocument := &models.ApplicationInfoFullData{}
@@ -176,6 +364,10 @@ func UpsertAppInfoToDb(appInfo *models.ApplicationInfoFullData) error {
 			"history.latest":                      updateLatest,
 			fmt.Sprintf("history.%s", versionKey): updateVersion,
 		},
+		// an app coming back to the catalog is no longer removed
+		"$unset": bson.M{
+			"removedAt": "",
+		},
 	}
 	opts := options.FindOneAndUpdate().SetUpsert(true)
 
@@ -207,6 +399,7 @@ func getUpdatesLatest(appInfoNew *models.ApplicationInfoFullData) *bson.M {
 
 	latest["name"] = appInfoNew.History["latest"].Name
 	latest["lastCommitHash"] = appInfoNew.History["latest"].LastCommitHash
+	latest["source"] = appInfoNew.History["latest"].Source
 	latest["updateTime"] = appInfoNew.History["latest"].UpdateTime
 	latest["createTime"] = appInfoNew.History["latest"].CreateTime
 
@@ -238,6 +431,7 @@ func getUpdatesLatest(appInfoNew *models.ApplicationInfoFullData) *bson.M {
 	latest["permission"] = appInfoNew.History["latest"].Permission
 	latest["entrances"] = appInfoNew.History["latest"].Entrances
 	latest["middleware"] = appInfoNew.History["latest"].Middleware
+	latest["middlewareSummary"] = appInfoNew.History["latest"].MiddlewareSummary
 	latest["options"] = appInfoNew.History["latest"].Options
 	latest["locale"] = appInfoNew.History["latest"].Locale
 	latest["i18n"] = appInfoNew.History["latest"].I18n
@@ -251,6 +445,7 @@ func getUpdatesLatest(appInfoNew *models.ApplicationInfoFullData) *bson.M {
 	latest["legal"] = appInfoNew.History["latest"].Legal
 	//update["status"] = appInfoNew.Status
 	latest["appLabels"] = appInfoNew.History["latest"].AppLabels
+	latest["labels"] = appInfoNew.History["latest"].Labels
 	latest["modelSize"] = appInfoNew.History["latest"].ModelSize
 	latest["namespace"] = appInfoNew.History["latest"].Namespace
 	latest["onlyAdmin"] = appInfoNew.History["latest"].OnlyAdmin
@@ -268,6 +463,7 @@ func getUpdatesVersion(appInfoNew *models.ApplicationInfoFullData) *bson.M {
 	// version
 	version["name"] = appInfoNew.History["latest"].Name
 	version["lastCommitHash"] = appInfoNew.History["latest"].LastCommitHash
+	version["source"] = appInfoNew.History["latest"].Source
 	version["updateTime"] = appInfoNew.History["latest"].UpdateTime
 	version["createTime"] = appInfoNew.History["latest"].CreateTime
 
@@ -298,6 +494,7 @@ func getUpdatesVersion(appInfoNew *models.ApplicationInfoFullData) *bson.M {
 	version["permission"] = appInfoNew.History["latest"].Permission
 	version["entrances"] = appInfoNew.History["latest"].Entrances
 	version["middleware"] = appInfoNew.History["latest"].Middleware
+	version["middlewareSummary"] = appInfoNew.History["latest"].MiddlewareSummary
 	version["options"] = appInfoNew.History["latest"].Options
 	version["locale"] = appInfoNew.History["latest"].Locale
 	version["i18n"] = appInfoNew.History["latest"].I18n
@@ -311,6 +508,7 @@ func getUpdatesVersion(appInfoNew *models.ApplicationInfoFullData) *bson.M {
 	version["legal"] = appInfoNew.History["latest"].Legal
 
 	version["appLabels"] = appInfoNew.History["latest"].AppLabels
+	version["labels"] = appInfoNew.History["latest"].Labels
 	version["modelSize"] = appInfoNew.History["latest"].ModelSize
 	version["namespace"] = appInfoNew.History["latest"].Namespace
 	version["onlyAdmin"] = appInfoNew.History["latest"].OnlyAdmin
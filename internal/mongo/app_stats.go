@@ -91,7 +91,7 @@ func GetTopApps(count int64) ([]string, error) {
 	return names, nil
 }
 
-func GetTopApplicationInfos(category, ty string, excludedLabels []string, count int) ([]models.ApplicationInfoFullData, error) {
+func GetTopApplicationInfos(category, ty string, excludedLabels, middleware []string, count int) ([]models.ApplicationInfoFullData, error) {
 	lastCommitHash, err := GetLastCommitHashFromDB()
 	if err != nil {
 		return nil, err
@@ -115,7 +115,7 @@ func GetTopApplicationInfos(category, ty string, excludedLabels []string, count
 			},
 		},
 	}
-	filter := make(bson.M)
+	filter := bson.M{"removedAt": bson.M{"$exists": false}}
 	if lastCommitHash != "" {
 		filter["history.latest.lastCommitHash"] = lastCommitHash
 	}
@@ -134,6 +134,12 @@ func GetTopApplicationInfos(category, ty string, excludedLabels []string, count
 		}
 	}
 
+	err = addHiddenCategoriesFilter(filter, excludedLabels)
+	if err != nil {
+		return nil, err
+	}
+	addMiddlewareFilter(filter, middleware)
+
 	if category != "" {
 		categoriesRegex := bson.M{
 			"$regex": primitive.Regex{Pattern: fmt.Sprintf("^%s$", category), Options: "i"},
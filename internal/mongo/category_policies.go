@@ -0,0 +1,156 @@
+package mongo
+
+import (
+	"app-store-server/pkg/models"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var categoryPolicyIndexOnce sync.Once
+
+// categoryKey is the unique key of a policy, categories differing in case are one
+func categoryKey(category string) string {
+	return strings.ToLower(category)
+}
+
+func ensureCategoryPolicyIndexes() {
+	_, err := mgoClient.createIndexes(AppStoreDb, CategoryPoliciesCollection, []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		glog.Warningf("create category policy indexes err:%s", err.Error())
+	}
+}
+
+func GetCategoryPolicies() (list []*models.CategoryPolicy, err error) {
+	findOpts := options.Find().SetSort(bson.D{bson.E{Key: "key", Value: 1}})
+	cur, err := mgoClient.queryMany(AppStoreDb, CategoryPoliciesCollection, bson.M{}, findOpts)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		policy := &models.CategoryPolicy{}
+		if err := cur.Decode(policy); err != nil {
+			glog.Warningf("err:%s", err.Error())
+			continue
+		}
+		list = append(list, policy)
+	}
+
+	return list, cur.Err()
+}
+
+// UpsertCategoryPolicy sets the policy of a category, replacing the one it had.
+func UpsertCategoryPolicy(policy *models.CategoryPolicy) error {
+	categoryPolicyIndexOnce.Do(ensureCategoryPolicyIndexes)
+
+	filter := bson.M{"key": categoryKey(policy.Category)}
+	update := bson.M{
+		"$set": bson.M{
+			"key":        categoryKey(policy.Category),
+			"category":   policy.Category,
+			"action":     policy.Action,
+			"label":      policy.Label,
+			"updateTime": policy.UpdateTime,
+		},
+	}
+	opts := options.Update().SetUpsert(true)
+	_, err := mgoClient.updateOne(AppStoreDb, CategoryPoliciesCollection, filter, update, opts)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+// DeleteCategoryPolicy removes the policy of a category, it returns false when there is none.
+func DeleteCategoryPolicy(category string) (bool, error) {
+	res, err := mgoClient.deleteOne(AppStoreDb, CategoryPoliciesCollection, bson.M{"key": categoryKey(category)})
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return false, err
+	}
+
+	return res.DeletedCount > 0, nil
+}
+
+// SeedCategoryPolicies stores policies when no policy was set yet, later runs
+// leave the policies edited through the admin API alone.
+func SeedCategoryPolicies(policies []*models.CategoryPolicy) error {
+	count, err := mgoClient.count(AppStoreDb, CategoryPoliciesCollection, bson.M{})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, policy := range policies {
+		err = UpsertCategoryPolicy(policy)
+		if err != nil {
+			return err
+		}
+		glog.Infof("seeded category policy %s %s", policy.Action, policy.Category)
+	}
+
+	return nil
+}
+
+// HiddenCategories returns the categories whose apps a query leaves out: the
+// hidden ones, and the labeled ones whose label is excluded.
+func HiddenCategories(policies []*models.CategoryPolicy, excludedLabels []string) []string {
+	excluded := make(map[string]bool)
+	for _, l := range excludedLabels {
+		excluded[l] = true
+	}
+
+	var categories []string
+	for _, p := range policies {
+		if p.Action == models.CategoryActionHide || (p.Action == models.CategoryActionLabel && excluded[p.Label]) {
+			categories = append(categories, p.Category)
+		}
+	}
+
+	return categories
+}
+
+// addHiddenCategoriesFilter leaves the apps of the hidden categories out of filter.
+func addHiddenCategoriesFilter(filter bson.M, excludedLabels []string) error {
+	policies, err := GetCategoryPolicies()
+	if err != nil {
+		return fmt.Errorf("get category policies: %w", err)
+	}
+
+	hidden := HiddenCategories(policies, excludedLabels)
+	if len(hidden) == 0 {
+		return nil
+	}
+
+	regexes := make(bson.A, 0, len(hidden))
+	for _, c := range hidden {
+		regexes = append(regexes, primitive.Regex{Pattern: "^" + regexp.QuoteMeta(c) + "$", Options: "i"})
+	}
+	filter["$nor"] = bson.A{
+		bson.M{"history.latest.categories": bson.M{"$in": regexes}},
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"app-store-server/pkg/models"
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpsertCommitRejection replaces the rejection of a source with its latest one.
+func UpsertCommitRejection(rejection *models.CommitRejection) error {
+	filter := bson.M{"source": rejection.Source}
+	update := bson.M{"$set": rejection}
+	opts := options.Update().SetUpsert(true)
+	_, err := mgoClient.updateOne(AppStoreDb, CommitRejectionsCollection, filter, update, opts)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+// DeleteCommitRejection clears the rejection of a source once a commit of it is accepted.
+func DeleteCommitRejection(source string) error {
+	_, err := mgoClient.deleteOne(AppStoreDb, CommitRejectionsCollection, bson.M{"source": source})
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+// GetCommitRejections returns the latest rejection of every source that has one.
+func GetCommitRejections() (list []*models.CommitRejection, err error) {
+	cur, err := mgoClient.queryMany(AppStoreDb, CommitRejectionsCollection, bson.M{})
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		rejection := &models.CommitRejection{}
+		if err := cur.Decode(rejection); err != nil {
+			glog.Warningf("err:%s", err.Error())
+			continue
+		}
+		list = append(list, rejection)
+	}
+
+	return
+}
@@ -40,3 +40,152 @@ func GetLastCommitHashFromDB() (string, error) {
 
 	return result.LastCommitHash, nil
 }
+
+// SetLastCommitHashesToDB records the catalog hash together with the HEAD commit of every source.
+func SetLastCommitHashesToDB(hash string, sourceHashes map[string]string) error {
+	updatedDocument := &struct {
+		LastCommitHash string
+	}{}
+	update := bson.M{}
+	update["lastCommitHash"] = hash
+	update["sourceCommitHashes"] = sourceHashes
+	u := bson.M{"$set": update}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+
+	err := mgoClient.findOneAndUpdate(AppStoreDb, AppGitCollection, bson.D{}, u, opts).Decode(updatedDocument)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+func GetSourceCommitHashesFromDB() (map[string]string, error) {
+	result := struct {
+		SourceCommitHashes map[string]string `bson:"sourceCommitHashes"`
+	}{}
+	err := mgoClient.queryOne(AppStoreDb, AppGitCollection, bson.D{}).Decode(&result)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return nil, err
+	}
+
+	return result.SourceCommitHashes, nil
+}
+
+// SetSourcePinToDB records the ref a source is pinned to, "" marks it as tracking its branch.
+func SetSourcePinToDB(source, ref string) error {
+	updatedDocument := &struct {
+		LastCommitHash string
+	}{}
+	update := bson.M{}
+	update["sourcePins."+source] = ref
+	u := bson.M{"$set": update}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+
+	err := mgoClient.findOneAndUpdate(AppStoreDb, AppGitCollection, bson.D{}, u, opts).Decode(updatedDocument)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+func GetSourcePinsFromDB() (map[string]string, error) {
+	result := struct {
+		SourcePins map[string]string `bson:"sourcePins"`
+	}{}
+	err := mgoClient.queryOne(AppStoreDb, AppGitCollection, bson.D{}).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return nil, err
+	}
+
+	return result.SourcePins, nil
+}
+
+// BumpCatalogGeneration records that the app infos changed, e.g. an ingestion
+// completed. Caches of the catalog compare the generation to tell they are stale.
+func BumpCatalogGeneration() error {
+	updatedDocument := &struct {
+		LastCommitHash string
+	}{}
+	u := bson.M{"$inc": bson.M{"catalogGeneration": 1}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+
+	err := mgoClient.findOneAndUpdate(AppStoreDb, AppGitCollection, bson.D{}, u, opts).Decode(updatedDocument)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+func GetCatalogGeneration() (int64, error) {
+	result := struct {
+		CatalogGeneration int64 `bson:"catalogGeneration"`
+	}{}
+	err := mgoClient.queryOne(AppStoreDb, AppGitCollection, bson.D{}).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return 0, err
+	}
+
+	return result.CatalogGeneration, nil
+}
+
+// SetSyncRequestToDB records a sync requested on a replica that is not the
+// leader, the leader takes it with TakeSyncRequestFromDB.
+func SetSyncRequestToDB(trigger string) error {
+	updatedDocument := &struct {
+		LastCommitHash string
+	}{}
+	u := bson.M{"$set": bson.M{"syncRequest": trigger}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+
+	err := mgoClient.findOneAndUpdate(AppStoreDb, AppGitCollection, bson.D{}, u, opts).Decode(updatedDocument)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+// TakeSyncRequestFromDB clears the recorded sync request and returns its
+// trigger, "" when no sync was requested.
+func TakeSyncRequestFromDB() (string, error) {
+	result := struct {
+		SyncRequest string `bson:"syncRequest"`
+	}{}
+	filter := bson.M{"syncRequest": bson.M{"$exists": true, "$ne": ""}}
+	u := bson.M{"$unset": bson.M{"syncRequest": ""}}
+
+	err := mgoClient.findOneAndUpdate(AppStoreDb, AppGitCollection, filter, u).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return "", err
+	}
+
+	return result.SyncRequest, nil
+}
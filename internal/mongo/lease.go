@@ -0,0 +1,109 @@
+package mongo
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Lease is held by one replica until ExpireAt, the holder renews it before then.
+// Expired leases are removed by a TTL index.
+type Lease struct {
+	Name      string    `bson:"name" json:"name"`
+	Holder    string    `bson:"holder" json:"holder"`
+	RenewTime time.Time `bson:"renewTime" json:"renewTime"`
+	ExpireAt  time.Time `bson:"expireAt" json:"expireAt"`
+}
+
+var (
+	leaseIndexMu    sync.Mutex
+	leaseIndexReady bool
+)
+
+// ensureLeaseIndexes creates the lease indexes until it succeeds once. Without
+// the unique name index two replicas could insert a lease each.
+func ensureLeaseIndexes() error {
+	leaseIndexMu.Lock()
+	defer leaseIndexMu.Unlock()
+
+	if leaseIndexReady {
+		return nil
+	}
+
+	_, err := mgoClient.createIndexes(AppStoreDb, LeasesCollection, []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "expireAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		glog.Warningf("create lease indexes err:%s", err.Error())
+		return err
+	}
+	leaseIndexReady = true
+
+	return nil
+}
+
+// AcquireLease takes the lease name for holder, or renews it when holder already
+// has it. It returns false when another holder has a lease that has not expired.
+func AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	// nobody takes the lease before the indexes guard it
+	err := ensureLeaseIndexes()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"name": name,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expireAt": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"holder":    holder,
+			"renewTime": now,
+			"expireAt":  now.Add(ttl),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+
+	err = mgoClient.findOneAndUpdate(AppStoreDb, LeasesCollection, filter, update, opts).Err()
+	if err == nil || errors.Is(err, mongo.ErrNoDocuments) {
+		return true, nil
+	}
+	// the upsert collides with the unexpired lease of another holder
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	glog.Warningf("err:%s", err.Error())
+
+	return false, err
+}
+
+// GetLease returns the lease name, nil when nobody holds it.
+func GetLease(name string) (*Lease, error) {
+	lease := &Lease{}
+	err := mgoClient.queryOne(AppStoreDb, LeasesCollection, bson.M{"name": name}).Decode(lease)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return nil, err
+	}
+
+	return lease, nil
+}
@@ -0,0 +1,82 @@
+package mongo
+
+import (
+	"app-store-server/pkg/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// addMiddlewareFilter limits filter to the apps whose latest version requests
+// all of the middleware kinds, given by any of their names.
+func addMiddlewareFilter(filter bson.M, middleware []string) {
+	kinds := make([]string, 0, len(middleware))
+	seen := make(map[string]bool)
+	for _, m := range middleware {
+		if kind := models.NormalizeMiddlewareKind(m); kind != "" && !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return
+	}
+
+	filter["history.latest.middlewareSummary.kinds"] = bson.M{"$all": kinds}
+}
+
+// CountAppsByMiddleware counts the apps of the catalog requesting each kind of
+// middleware in their latest version.
+func CountAppsByMiddleware() ([]*models.MiddlewareCount, error) {
+	lastCommitHash, err := GetLastCommitHashFromDB()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"removedAt": bson.M{"$exists": false}}
+	if lastCommitHash != "" {
+		filter["history.latest.lastCommitHash"] = lastCommitHash
+	}
+
+	pipeline := []bson.M{
+		{
+			"$match": filter,
+		},
+		{
+			"$unwind": "$history.latest.middlewareSummary.middlewares",
+		},
+		{
+			"$group": bson.M{
+				"_id":       "$history.latest.middlewareSummary.middlewares.kind",
+				"apps":      bson.M{"$sum": 1},
+				"databases": bson.M{"$sum": "$history.latest.middlewareSummary.middlewares.databases"},
+				"distributedApps": bson.M{"$sum": bson.M{
+					"$cond": bson.A{bson.M{"$gt": bson.A{"$history.latest.middlewareSummary.middlewares.distributedDatabases", 0}}, 1, 0},
+				}},
+			},
+		},
+		{
+			"$sort": bson.D{
+				bson.E{Key: "apps", Value: -1},
+				bson.E{Key: "_id", Value: 1},
+			},
+		},
+	}
+
+	appInfoCollection := mgoClient.mgo.Database(AppStoreDb).Collection(AppInfosCollection)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	cursor, err := appInfoCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := []*models.MiddlewareCount{}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,51 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAddMiddlewareFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware []string
+		want       bson.M
+	}{
+		{
+			name: "no middleware",
+			want: bson.M{},
+		},
+		{
+			name:       "blank kinds",
+			middleware: []string{"", " "},
+			want:       bson.M{},
+		},
+		{
+			name:       "aliases match the kinds of the summaries",
+			middleware: []string{"PostgreSQL", "mongo"},
+			want: bson.M{"history.latest.middlewareSummary.kinds": bson.M{
+				"$all": []string{"postgres", "mongodb"},
+			}},
+		},
+		{
+			name:       "a kind given twice",
+			middleware: []string{"pg", "postgres", "redis"},
+			want: bson.M{"history.latest.middlewareSummary.kinds": bson.M{
+				"$all": []string{"postgres", "redis"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := bson.M{}
+			addMiddlewareFilter(filter, tt.middleware)
+
+			if !reflect.DeepEqual(filter, tt.want) {
+				t.Errorf("filter = %v, want %v", filter, tt.want)
+			}
+		})
+	}
+}
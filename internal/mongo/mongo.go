@@ -27,6 +27,11 @@ const (
 	AppTopicsCollection             = "AppTopics"
 	AppRecommendsCollection         = "AppRecommends"
 	AppCategoryRecommendsCollection = "AppCategoryRecommends"
+	SyncRunsCollection              = "SyncRuns"
+	LeasesCollection                = "Leases"
+	ValidationReportsCollection     = "ValidationReports"
+	CategoryPoliciesCollection      = "CategoryPolicies"
+	CommitRejectionsCollection      = "CommitRejections"
 )
 
 var mgoClient *Client
@@ -149,3 +154,11 @@ func (mc *Client) findOneAndUpdate(db, collection string, filter, update interfa
 
 	return coll.FindOneAndUpdate(ctx, filter, update, opts...)
 }
+
+func (mc *Client) createIndexes(db, collection string, models []mongo.IndexModel, opts ...*options.CreateIndexesOptions) ([]string, error) {
+	coll := mc.mgo.Database(db).Collection(collection)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	return coll.Indexes().CreateMany(ctx, models, opts...)
+}
@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	"app-store-server/pkg/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/glog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpsertSyncRun writes the current state of a run.
+func UpsertSyncRun(run *models.SyncRun) error {
+	filter := bson.M{"id": run.Id}
+	update := bson.M{"$set": run}
+	opts := options.Update().SetUpsert(true)
+	_, err := mgoClient.updateOne(AppStoreDb, SyncRunsCollection, filter, update, opts)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+// GetSyncRuns returns the runs newest first, filtered by status when it is not empty.
+func GetSyncRuns(offset, size int64, status string) (list []*models.SyncRun, count int64, err error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	findOpts := options.Find().
+		SetSort(bson.D{bson.E{Key: "startTime", Value: -1}}).
+		SetSkip(offset).
+		SetLimit(size)
+
+	cur, err := mgoClient.queryMany(AppStoreDb, SyncRunsCollection, filter, findOpts)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		run := &models.SyncRun{}
+		if err := cur.Decode(run); err != nil {
+			glog.Warningf("err:%s", err.Error())
+			continue
+		}
+		list = append(list, run)
+	}
+
+	count, err = mgoClient.count(AppStoreDb, SyncRunsCollection, filter)
+	return
+}
+
+// GetLastSyncRun returns the newest run with status, or any status when it is empty.
+func GetLastSyncRun(status string) (*models.SyncRun, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	run := &models.SyncRun{}
+	opts := options.FindOne().SetSort(bson.D{bson.E{Key: "startTime", Value: -1}})
+	err := mgoClient.queryOne(AppStoreDb, SyncRunsCollection, filter, opts).Decode(run)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// TrimSyncRuns keeps the newest keep runs.
+func TrimSyncRuns(keep int64) error {
+	opts := options.FindOne().
+		SetSort(bson.D{bson.E{Key: "startTime", Value: -1}}).
+		SetSkip(keep - 1)
+
+	oldest := &models.SyncRun{}
+	err := mgoClient.queryOne(AppStoreDb, SyncRunsCollection, bson.M{}, opts).Decode(oldest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = mgoClient.deleteMany(AppStoreDb, SyncRunsCollection, bson.M{"startTime": bson.M{"$lt": oldest.StartTime}})
+	return err
+}
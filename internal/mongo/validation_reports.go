@@ -0,0 +1,88 @@
+package mongo
+
+import (
+	"app-store-server/pkg/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/glog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpsertValidationReport replaces the report of an app with its latest one.
+func UpsertValidationReport(report *models.ValidationReport) error {
+	filter := bson.M{"name": report.Name}
+	update := bson.M{"$set": report}
+	opts := options.Update().SetUpsert(true)
+	_, err := mgoClient.updateOne(AppStoreDb, ValidationReportsCollection, filter, update, opts)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
+
+// GetValidationReport returns the report of an app, nil when it has none.
+func GetValidationReport(name string) (*models.ValidationReport, error) {
+	report := &models.ValidationReport{}
+	err := mgoClient.queryOne(AppStoreDb, ValidationReportsCollection, bson.M{"name": name}).Decode(report)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetValidationReports returns the reports by name, only the ones with errors
+// when invalidOnly is set.
+func GetValidationReports(offset, size int64, invalidOnly bool) (list []*models.ValidationReport, count int64, err error) {
+	filter := bson.M{}
+	if invalidOnly {
+		filter["valid"] = false
+	}
+
+	findOpts := options.Find().
+		SetSort(bson.D{bson.E{Key: "name", Value: 1}}).
+		SetSkip(offset).
+		SetLimit(size)
+
+	cur, err := mgoClient.queryMany(AppStoreDb, ValidationReportsCollection, filter, findOpts)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		report := &models.ValidationReport{}
+		if err := cur.Decode(report); err != nil {
+			glog.Warningf("err:%s", err.Error())
+			continue
+		}
+		list = append(list, report)
+	}
+
+	count, err = mgoClient.count(AppStoreDb, ValidationReportsCollection, filter)
+	return
+}
+
+// DeleteValidationReportsExcept deletes the reports of the app directories not
+// in names, reports are keyed by the name the directory is published under.
+func DeleteValidationReportsExcept(names []string) error {
+	filter := bson.M{"name": bson.M{"$nin": names}}
+	_, err := mgoClient.deleteMany(AppStoreDb, ValidationReportsCollection, filter)
+	if err != nil {
+		glog.Warningf("err:%s", err.Error())
+	}
+
+	return err
+}
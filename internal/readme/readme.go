@@ -0,0 +1,79 @@
+package readme
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday/v2"
+)
+
+var (
+	// inlineLinkRegex matches the target of [text](target "title") and ![alt](target)
+	inlineLinkRegex = regexp.MustCompile(`(\]\(\s*<?)([^)\s>]+)`)
+	// refLinkRegex matches the target of a reference definition [id]: target
+	refLinkRegex = regexp.MustCompile(`(?m)^(\s{0,3}\[[^\]]+\]:\s*<?)([^\s>]+)`)
+	// htmlLinkRegex matches the src and href attributes of inline HTML
+	htmlLinkRegex = regexp.MustCompile(`(?i)(\b(?:src|href)\s*=\s*["'])([^"']+)`)
+)
+
+var policy = bluemonday.UGCPolicy()
+
+// RewriteLinks points the relative links and images of a markdown document at
+// the assets of the app. dir is the directory of the document inside the app
+// directory, assetURL returns the URL of a file of the app directory. Links
+// leaving the app directory are kept as they are.
+func RewriteLinks(content []byte, dir string, assetURL func(p string) string) []byte {
+	rewrite := func(re *regexp.Regexp, content []byte) []byte {
+		return re.ReplaceAllFunc(content, func(m []byte) []byte {
+			sub := re.FindSubmatch(m)
+			target, suffix, ok := resolve(string(sub[2]), dir)
+			if !ok {
+				return m
+			}
+			return append(append([]byte{}, sub[1]...), assetURL(target)+suffix...)
+		})
+	}
+
+	content = rewrite(inlineLinkRegex, content)
+	content = rewrite(refLinkRegex, content)
+	content = rewrite(htmlLinkRegex, content)
+
+	return content
+}
+
+// resolve returns the path inside the app directory a relative link points
+// to, and the query and fragment of the link.
+func resolve(link, dir string) (string, string, bool) {
+	if strings.HasPrefix(link, "/") || strings.HasPrefix(link, "#") {
+		return "", "", false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", "", false
+	}
+
+	p := path.Join(dir, u.Path)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", "", false
+	}
+
+	suffix := ""
+	if u.RawQuery != "" {
+		suffix += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		suffix += "#" + u.EscapedFragment()
+	}
+
+	return p, suffix, true
+}
+
+// RenderHTML renders a markdown document to HTML with everything unsafe,
+// scripts, event handlers, javascript: links, removed.
+func RenderHTML(content []byte) []byte {
+	return policy.SanitizeBytes(blackfriday.Run(content))
+}
@@ -0,0 +1,181 @@
+package resolver
+
+import (
+	"app-store-server/pkg/models"
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const ConflictTypeApplication = "application"
+
+// entry returns the version of an app in the catalog, the latest one when the
+// version is not there.
+func (c Catalog) entry(name, version string) (models.ApplicationInfoEntry, bool) {
+	app, ok := c[name]
+	if !ok {
+		return models.ApplicationInfoEntry{}, false
+	}
+
+	for _, e := range app.History {
+		if e.Version == version {
+			return e, true
+		}
+	}
+	e, ok := app.History["latest"]
+
+	return e, ok
+}
+
+// Pick returns the version of an app to check, the newest running on the
+// system version when version is empty.
+func (c Catalog) Pick(name, version string, system *semver.Version) (models.ApplicationInfoEntry, error) {
+	if _, ok := c[name]; !ok {
+		return models.ApplicationInfoEntry{}, ErrAppNotFound
+	}
+
+	for _, cand := range c.candidates(name, system) {
+		if version == "" || cand.entry.Version == version {
+			return cand.entry, nil
+		}
+	}
+
+	return models.ApplicationInfoEntry{}, ErrNoVersion
+}
+
+func conflictsWith(entry models.ApplicationInfoEntry, name string) bool {
+	for _, c := range entry.Options.Conflicts {
+		if c.Name == name && (c.Type == "" || c.Type == ConflictTypeApplication) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func clusterScoped(entry models.ApplicationInfoEntry) bool {
+	return entry.Options.AppScope != nil && entry.Options.AppScope.ClusterScoped
+}
+
+func referable(entry models.ApplicationInfoEntry, name string) bool {
+	if entry.Options.AppScope == nil {
+		return true
+	}
+	for _, ref := range entry.Options.AppScope.AppRef {
+		if ref == name || ref == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckInstall tells whether candidate can be installed next to the installed
+// apps, given as name to version, or replace its installed version. Conflicts
+// are checked both ways, installed apps are looked up in the catalog at their
+// installed version.
+func CheckInstall(catalog Catalog, candidate models.ApplicationInfoEntry, installed map[string]string) *models.InstallCheckRes {
+	res := &models.InstallCheckRes{
+		Name:    candidate.Name,
+		Version: candidate.Version,
+		Action:  models.InstallActionInstall,
+		Reasons: []models.InstallReason{},
+	}
+	addReason := func(kind, app, constraint, message string) {
+		res.Reasons = append(res.Reasons, models.InstallReason{
+			Kind:       kind,
+			App:        app,
+			Constraint: constraint,
+			Message:    message,
+		})
+	}
+
+	names := make([]string, 0, len(installed))
+	for name := range installed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if current, ok := installed[candidate.Name]; ok {
+		res.Action = models.InstallActionUpgrade
+		res.InstalledVersion = current
+		cv, err1 := semver.NewVersion(current)
+		nv, err2 := semver.NewVersion(candidate.Version)
+		if err1 == nil && err2 == nil && !nv.GreaterThan(cv) {
+			addReason(models.InstallReasonNotNewer, candidate.Name, "",
+				fmt.Sprintf("%s %s is installed, %s is not newer", candidate.Name, current, candidate.Version))
+		}
+	}
+
+	for _, name := range names {
+		if name == candidate.Name {
+			continue
+		}
+
+		if conflictsWith(candidate, name) {
+			addReason(models.InstallReasonConflict, name, "",
+				fmt.Sprintf("%s conflicts with the installed %s", candidate.Name, name))
+		}
+
+		other, ok := catalog.entry(name, installed[name])
+		if !ok {
+			continue
+		}
+		if conflictsWith(other, candidate.Name) {
+			addReason(models.InstallReasonConflictedBy, name, "",
+				fmt.Sprintf("the installed %s conflicts with %s", name, candidate.Name))
+		}
+
+		// an upgrade must keep satisfying the apps depending on the candidate
+		if res.Action != models.InstallActionUpgrade {
+			continue
+		}
+		for _, dep := range applicationDependencies(other) {
+			if dep.Name != candidate.Name || dep.Version == "" {
+				continue
+			}
+			if !satisfied(dep.Version, candidate.Version) {
+				addReason(models.InstallReasonBreaksDependent, name, dep.Version,
+					fmt.Sprintf("the installed %s requires %s %s", name, candidate.Name, dep.Version))
+			}
+		}
+	}
+
+	for _, dep := range applicationDependencies(candidate) {
+		version, isInstalled := installed[dep.Name]
+		depEntry, inCatalog := catalog.entry(dep.Name, version)
+
+		if isInstalled {
+			if dep.Version != "" && !satisfied(dep.Version, version) {
+				addReason(models.InstallReasonDependencyVersion, dep.Name, dep.Version,
+					fmt.Sprintf("%s requires %s %s, %s is installed", candidate.Name, dep.Name, dep.Version, version))
+			}
+		} else if inCatalog && clusterScoped(depEntry) && dep.Mandatory {
+			addReason(models.InstallReasonClusterScopedMissing, dep.Name, dep.Version,
+				fmt.Sprintf("%s requires the cluster-scoped %s, which is not installed", candidate.Name, dep.Name))
+		}
+
+		if inCatalog && clusterScoped(depEntry) && !referable(depEntry, candidate.Name) {
+			addReason(models.InstallReasonAppRef, dep.Name, "",
+				fmt.Sprintf("the cluster-scoped %s does not list %s in its appRef", dep.Name, candidate.Name))
+		}
+	}
+
+	res.Installable = len(res.Reasons) == 0
+
+	return res
+}
+
+func satisfied(constraint, version string) bool {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	return c.Check(v)
+}
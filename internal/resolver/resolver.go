@@ -0,0 +1,443 @@
+package resolver
+
+import (
+	"app-store-server/pkg/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	DependencyTypeApplication = "application"
+	DependencyTypeSystem      = "system"
+	// SystemName is the system dependency apps declare the system versions they run on with
+	SystemName = "olares"
+)
+
+var (
+	ErrAppNotFound = errors.New("app not found")
+	ErrNoVersion   = errors.New("no version of the app runs on the system version")
+)
+
+// Catalog holds the apps dependencies are resolved from, by name.
+type Catalog map[string]*models.ApplicationInfoFullData
+
+// NewCatalog indexes a list of apps by name.
+func NewCatalog(apps []*models.ApplicationInfoFullData) Catalog {
+	c := make(Catalog, len(apps))
+	for _, app := range apps {
+		c[app.Name] = app
+	}
+
+	return c
+}
+
+type candidate struct {
+	entry   models.ApplicationInfoEntry
+	version *semver.Version
+}
+
+// candidates returns the versions of an app that run on the system version,
+// newest first. Every version runs when system is nil.
+func (c Catalog) candidates(name string, system *semver.Version) []candidate {
+	app, ok := c[name]
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var list []candidate
+	for _, entry := range app.History {
+		if seen[entry.Version] {
+			continue
+		}
+		v, err := semver.NewVersion(entry.Version)
+		if err != nil || !runsOn(entry, system) {
+			continue
+		}
+		seen[entry.Version] = true
+		list = append(list, candidate{entry: entry, version: v})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].version.GreaterThan(list[j].version)
+	})
+
+	return list
+}
+
+// runsOn reports whether a version of an app declares the system version in its
+// system dependency, like the version filter of the app list.
+func runsOn(entry models.ApplicationInfoEntry, system *semver.Version) bool {
+	if system == nil {
+		return true
+	}
+
+	for _, dep := range entry.Options.Dependencies {
+		if dep.Name != SystemName || dep.Type != DependencyTypeSystem {
+			continue
+		}
+		constraint, err := semver.NewConstraint(dep.Version)
+		if err == nil && constraint.Check(system) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// edge is an application dependency of a picked app version
+type edge struct {
+	from       string
+	dep        models.Dependency
+	constraint *semver.Constraints
+}
+
+func applicationDependencies(entry models.ApplicationInfoEntry) []models.Dependency {
+	var deps []models.Dependency
+	for _, dep := range entry.Options.Dependencies {
+		if dep.Type == DependencyTypeApplication && dep.Name != "" && !dep.SelfRely {
+			deps = append(deps, dep)
+		}
+	}
+
+	return deps
+}
+
+type resolver struct {
+	catalog Catalog
+	system  *semver.Version
+	root    string
+
+	// picked holds the version picked for each app reachable from the root
+	picked   map[string]candidate
+	edges    map[string][]edge
+	problems []models.DependencyProblem
+}
+
+// Resolve picks a version of the app and of every app it depends on, for the
+// system version or regardless of it when system is nil. Each app gets the
+// newest version satisfying all the constraints on it.
+func Resolve(catalog Catalog, name string, system *semver.Version) (*models.DependencyResolution, error) {
+	roots := catalog.candidates(name, system)
+	if len(roots) == 0 {
+		if _, ok := catalog[name]; !ok {
+			return nil, ErrAppNotFound
+		}
+		return nil, ErrNoVersion
+	}
+
+	r := &resolver{
+		catalog: catalog,
+		system:  system,
+		root:    name,
+		picked:  map[string]candidate{name: roots[0]},
+	}
+	r.solve()
+
+	res := &models.DependencyResolution{
+		Name:       name,
+		Resolvable: true,
+	}
+	if system != nil {
+		res.SystemVersion = system.String()
+	}
+
+	res.Tree = r.tree(&models.DependencyNode{Name: name, Mandatory: true}, nil)
+	res.Apps = r.installOrder()
+	res.Problems = r.problems
+	if res.Problems == nil {
+		res.Problems = []models.DependencyProblem{}
+	}
+	for _, p := range res.Problems {
+		if p.Mandatory {
+			res.Resolvable = false
+			break
+		}
+	}
+
+	return res, nil
+}
+
+// solve repeats picking versions until the picks satisfy the constraints of
+// the picks, a new pick can add or drop constraints on other apps.
+func (r *resolver) solve() {
+	for i := 0; i <= len(r.catalog); i++ {
+		r.collectEdges()
+		if !r.repick() {
+			break
+		}
+	}
+
+	r.collectEdges()
+	r.problems = nil
+	r.checkEdges()
+	r.checkCycles()
+}
+
+// collectEdges gathers the application dependencies of the picked versions
+// reachable from the root.
+func (r *resolver) collectEdges() {
+	r.edges = make(map[string][]edge)
+	visited := map[string]bool{r.root: true}
+	queue := []string{r.root}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for _, dep := range applicationDependencies(r.picked[name].entry) {
+			e := edge{from: name, dep: dep}
+			if dep.Version != "" {
+				// a constraint that does not parse is reported by checkEdges
+				e.constraint, _ = semver.NewConstraint(dep.Version)
+			}
+			r.edges[dep.Name] = append(r.edges[dep.Name], e)
+
+			if _, ok := r.picked[dep.Name]; !ok {
+				if c, ok := r.best(dep.Name); ok {
+					r.picked[dep.Name] = c
+				}
+			}
+			if _, ok := r.picked[dep.Name]; ok && !visited[dep.Name] {
+				visited[dep.Name] = true
+				queue = append(queue, dep.Name)
+			}
+		}
+	}
+
+	// drop the picks no longer reachable
+	for name := range r.picked {
+		if !visited[name] {
+			delete(r.picked, name)
+		}
+	}
+}
+
+// best returns the newest version of an app satisfying every constraint on it.
+func (r *resolver) best(name string) (candidate, bool) {
+	for _, c := range r.catalog.candidates(name, r.system) {
+		if r.satisfies(name, c.version) {
+			return c, true
+		}
+	}
+
+	return candidate{}, false
+}
+
+func (r *resolver) satisfies(name string, v *semver.Version) bool {
+	for _, e := range r.edges[name] {
+		if e.constraint != nil && !e.constraint.Check(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// repick moves the apps whose pick does not satisfy their constraints, or is
+// not the newest that does, to the best version. It reports whether a pick changed.
+func (r *resolver) repick() bool {
+	changed := false
+	for name := range r.edges {
+		if name == r.root {
+			continue
+		}
+
+		c, ok := r.best(name)
+		current, picked := r.picked[name]
+		switch {
+		case !ok && picked:
+			delete(r.picked, name)
+			changed = true
+		case ok && (!picked || !current.version.Equal(c.version)):
+			r.picked[name] = c
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func (r *resolver) addProblem(e edge, kind, message string) {
+	r.problems = append(r.problems, models.DependencyProblem{
+		App:        e.from,
+		Dependency: e.dep.Name,
+		Kind:       kind,
+		Constraint: e.dep.Version,
+		Mandatory:  e.dep.Mandatory,
+		Message:    message,
+	})
+}
+
+func (r *resolver) checkEdges() {
+	names := make([]string, 0, len(r.edges))
+	for name := range r.edges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, e := range r.edges[name] {
+			if e.dep.Version != "" && e.constraint == nil {
+				r.addProblem(e, models.DependencyProblemInvalid, fmt.Sprintf("constraint %q does not parse", e.dep.Version))
+				continue
+			}
+
+			if _, ok := r.catalog[name]; !ok {
+				r.addProblem(e, models.DependencyProblemMissing, fmt.Sprintf("%s is not in the catalog", name))
+				continue
+			}
+
+			// the picks can keep alternating between constraints no version
+			// satisfies together, the last one is reported then
+			c, ok := r.picked[name]
+			if !ok || (name != r.root && !r.satisfies(name, c.version)) {
+				r.addProblem(e, models.DependencyProblemUnsatisfiable, fmt.Sprintf("no version of %s satisfies %s", name, r.constraintsOf(name)))
+				continue
+			}
+			if name == r.root && e.constraint != nil && !e.constraint.Check(c.version) {
+				r.addProblem(e, models.DependencyProblemUnsatisfiable, fmt.Sprintf("%s %s does not satisfy %q", name, c.entry.Version, e.dep.Version))
+			}
+		}
+	}
+}
+
+// constraintsOf describes the constraints on an app for a problem message.
+func (r *resolver) constraintsOf(name string) string {
+	var list []string
+	for _, e := range r.edges[name] {
+		constraint := e.dep.Version
+		if constraint == "" {
+			constraint = "*"
+		}
+		list = append(list, fmt.Sprintf("%q of %s", constraint, e.from))
+	}
+	if r.system != nil {
+		list = append(list, fmt.Sprintf("system %s", r.system))
+	}
+
+	return strings.Join(list, ", ")
+}
+
+// checkCycles reports the dependencies leading back to an app on the path from the root.
+func (r *resolver) checkCycles() {
+	reported := make(map[string]bool)
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		path = append(path, name)
+		for _, dep := range applicationDependencies(r.picked[name].entry) {
+			if _, ok := r.picked[dep.Name]; !ok {
+				continue
+			}
+
+			if i := indexOf(path, dep.Name); i >= 0 {
+				cycle := strings.Join(append(append([]string{}, path[i:]...), dep.Name), " -> ")
+				if !reported[cycle] {
+					reported[cycle] = true
+					r.addProblem(edge{from: name, dep: dep}, models.DependencyProblemCycle, "cycle "+cycle)
+				}
+				continue
+			}
+			visit(dep.Name, path)
+		}
+	}
+	visit(r.root, nil)
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// tree fills node with the picked version of its app and its dependencies.
+func (r *resolver) tree(node *models.DependencyNode, path []string) *models.DependencyNode {
+	c, ok := r.picked[node.Name]
+	if !ok {
+		return node
+	}
+	node.Version = c.entry.Version
+	node.ChartName = c.entry.ChartName
+
+	if indexOf(path, node.Name) >= 0 {
+		node.Cycle = true
+		return node
+	}
+	path = append(path, node.Name)
+
+	for _, dep := range applicationDependencies(c.entry) {
+		node.Dependencies = append(node.Dependencies, r.tree(&models.DependencyNode{
+			Name:       dep.Name,
+			Constraint: dep.Version,
+			Mandatory:  dep.Mandatory,
+		}, path))
+	}
+
+	return node
+}
+
+// installOrder lists the picked apps, each after the apps it depends on. The
+// apps of a cycle come in the order they are reached.
+func (r *resolver) installOrder() []models.ResolvedApp {
+	var apps []models.ResolvedApp
+	done := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		c, ok := r.picked[name]
+		if !ok || done[name] || visiting[name] {
+			return
+		}
+		visiting[name] = true
+		for _, dep := range applicationDependencies(c.entry) {
+			visit(dep.Name)
+		}
+		visiting[name] = false
+
+		done[name] = true
+		apps = append(apps, models.ResolvedApp{
+			Name:      name,
+			Version:   c.entry.Version,
+			ChartName: c.entry.ChartName,
+		})
+	}
+	visit(r.root)
+
+	return apps
+}
+
+// CheckCatalog resolves the newest version of every app of the catalog and
+// returns the problems found, regardless of the system version.
+func CheckCatalog(catalog Catalog) []models.DependencyProblem {
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []models.DependencyProblem
+	seen := make(map[models.DependencyProblem]bool)
+	for _, name := range names {
+		res, err := Resolve(catalog, name, nil)
+		if err != nil {
+			continue
+		}
+		for _, p := range res.Problems {
+			if !seen[p] {
+				seen[p] = true
+				problems = append(problems, p)
+			}
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,314 @@
+package resolver
+
+import (
+	"app-store-server/pkg/models"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+func dep(name, constraint string) models.Dependency {
+	return models.Dependency{Name: name, Version: constraint, Type: DependencyTypeApplication, Mandatory: true}
+}
+
+func optional(name, constraint string) models.Dependency {
+	d := dep(name, constraint)
+	d.Mandatory = false
+	return d
+}
+
+func system(constraint string) models.Dependency {
+	return models.Dependency{Name: SystemName, Version: constraint, Type: DependencyTypeSystem, Mandatory: true}
+}
+
+func version(name, v string, deps ...models.Dependency) models.ApplicationInfoEntry {
+	return models.ApplicationInfoEntry{
+		Name:      name,
+		Version:   v,
+		ChartName: fmt.Sprintf("%s-%s.tgz", name, v),
+		Options:   models.Options{Dependencies: deps},
+	}
+}
+
+// app builds an app of the catalog, the last version is the latest one.
+func app(versions ...models.ApplicationInfoEntry) *models.ApplicationInfoFullData {
+	history := make(map[string]models.ApplicationInfoEntry)
+	for _, v := range versions {
+		history[v.Version] = v
+	}
+	history["latest"] = versions[len(versions)-1]
+
+	return &models.ApplicationInfoFullData{Name: versions[0].Name, History: history}
+}
+
+func picks(res *models.DependencyResolution) []string {
+	var list []string
+	for _, a := range res.Apps {
+		list = append(list, a.Name+"@"+a.Version)
+	}
+
+	return list
+}
+
+func kinds(res *models.DependencyResolution) []string {
+	var list []string
+	for _, p := range res.Problems {
+		list = append(list, p.Dependency+":"+p.Kind)
+	}
+
+	return list
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		apps       []*models.ApplicationInfoFullData
+		picks      []string
+		problems   []string
+		resolvable bool
+	}{
+		{
+			name: "no dependencies picks the newest version",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0"), version("root", "1.2.0")),
+			},
+			picks:      []string{"root@1.2.0"},
+			resolvable: true,
+		},
+		{
+			name: "newest version satisfying the constraint",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", ">=1.0.0 <2.0.0"))),
+				app(version("a", "1.0.0"), version("a", "1.5.0"), version("a", "2.0.0")),
+			},
+			picks:      []string{"a@1.5.0", "root@1.0.0"},
+			resolvable: true,
+		},
+		{
+			name: "a later constraint repicks an app picked before",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", ""), dep("b", ""))),
+				app(version("a", "1.0.0", dep("b", "<2.0.0"))),
+				app(version("b", "1.0.0"), version("b", "2.0.0")),
+			},
+			picks:      []string{"b@1.0.0", "a@1.0.0", "root@1.0.0"},
+			resolvable: true,
+		},
+		{
+			name: "a repick drops the dependencies of the old pick",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", ""), dep("b", ""))),
+				app(version("a", "1.0.0"), version("a", "2.0.0", dep("c", ""))),
+				app(version("b", "1.0.0", dep("a", "<2.0.0"))),
+				app(version("c", "1.0.0")),
+			},
+			picks:      []string{"a@1.0.0", "b@1.0.0", "root@1.0.0"},
+			resolvable: true,
+		},
+		{
+			name: "diamond picks one version satisfying both sides",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", ""), dep("b", ""))),
+				app(version("a", "1.0.0", dep("c", ">=1.0.0"))),
+				app(version("b", "1.0.0", dep("c", "<1.5.0"))),
+				app(version("c", "1.0.0"), version("c", "1.2.0"), version("c", "2.0.0")),
+			},
+			picks:      []string{"c@1.2.0", "a@1.0.0", "b@1.0.0", "root@1.0.0"},
+			resolvable: true,
+		},
+		{
+			name: "diamond without a common version",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", ""), dep("b", ""))),
+				app(version("a", "1.0.0", dep("c", ">=2.0.0"))),
+				app(version("b", "1.0.0", dep("c", "<2.0.0"))),
+				app(version("c", "1.0.0"), version("c", "2.0.0")),
+			},
+			picks:      []string{"c@2.0.0", "a@1.0.0", "b@1.0.0", "root@1.0.0"},
+			problems:   []string{"c:unsatisfiable", "c:unsatisfiable"},
+			resolvable: false,
+		},
+		{
+			name: "cycle back to the root",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", ""))),
+				app(version("a", "1.0.0", dep("root", ""))),
+			},
+			picks:      []string{"a@1.0.0", "root@1.0.0"},
+			problems:   []string{"root:cycle"},
+			resolvable: false,
+		},
+		{
+			name: "cycle below the root",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", ""))),
+				app(version("a", "1.0.0", dep("b", ""))),
+				app(version("b", "1.0.0", optional("a", ""))),
+			},
+			picks:      []string{"b@1.0.0", "a@1.0.0", "root@1.0.0"},
+			problems:   []string{"a:cycle"},
+			resolvable: true,
+		},
+		{
+			name: "root constraint not met by the root pick",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", "")), version("root", "2.0.0", dep("a", ""))),
+				app(version("a", "1.0.0", optional("root", "<2.0.0"))),
+			},
+			picks:      []string{"a@1.0.0", "root@2.0.0"},
+			problems:   []string{"root:unsatisfiable", "root:cycle"},
+			resolvable: true,
+		},
+		{
+			name: "missing mandatory dependency",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("x", ""))),
+			},
+			picks:      []string{"root@1.0.0"},
+			problems:   []string{"x:missing"},
+			resolvable: false,
+		},
+		{
+			name: "missing optional dependency",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", optional("x", ""))),
+			},
+			picks:      []string{"root@1.0.0"},
+			problems:   []string{"x:missing"},
+			resolvable: true,
+		},
+		{
+			name: "unsatisfiable constraint",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", ">=3.0.0"))),
+				app(version("a", "1.0.0"), version("a", "2.0.0")),
+			},
+			picks:      []string{"root@1.0.0"},
+			problems:   []string{"a:unsatisfiable"},
+			resolvable: false,
+		},
+		{
+			name: "constraint that does not parse",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", dep("a", "not a version"))),
+				app(version("a", "1.0.0")),
+			},
+			picks:      []string{"a@1.0.0", "root@1.0.0"},
+			problems:   []string{"a:invalid-constraint"},
+			resolvable: false,
+		},
+		{
+			name: "self reliant and system dependencies are not resolved",
+			apps: []*models.ApplicationInfoFullData{
+				app(version("root", "1.0.0", system(">=1.0.0"), models.Dependency{Name: "a", Type: DependencyTypeApplication, SelfRely: true})),
+			},
+			picks:      []string{"root@1.0.0"},
+			resolvable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Resolve(NewCatalog(tt.apps), "root", nil)
+			if err != nil {
+				t.Fatalf("Resolve err: %v", err)
+			}
+
+			if got := picks(res); !reflect.DeepEqual(got, tt.picks) {
+				t.Errorf("apps = %v, want %v", got, tt.picks)
+			}
+			if got := kinds(res); !reflect.DeepEqual(got, tt.problems) {
+				t.Errorf("problems = %v, want %v", got, tt.problems)
+			}
+			if res.Resolvable != tt.resolvable {
+				t.Errorf("resolvable = %v, want %v", res.Resolvable, tt.resolvable)
+			}
+		})
+	}
+}
+
+func TestResolveSystemVersion(t *testing.T) {
+	catalog := NewCatalog([]*models.ApplicationInfoFullData{
+		app(
+			version("root", "1.0.0", system(">=1.10.0-0"), dep("a", "")),
+			version("root", "2.0.0", system(">=1.12.0-0"), dep("a", "")),
+		),
+		app(
+			version("a", "1.0.0", system(">=1.10.0-0")),
+			version("a", "2.0.0", system(">=1.12.0-0")),
+		),
+	})
+
+	tests := []struct {
+		system string
+		picks  []string
+		err    error
+	}{
+		{system: "1.11.0", picks: []string{"a@1.0.0", "root@1.0.0"}},
+		{system: "1.12.3", picks: []string{"a@2.0.0", "root@2.0.0"}},
+		{system: "1.9.0", err: ErrNoVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.system, func(t *testing.T) {
+			res, err := Resolve(catalog, "root", semver.MustParse(tt.system))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Resolve err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := picks(res); !reflect.DeepEqual(got, tt.picks) {
+				t.Errorf("apps = %v, want %v", got, tt.picks)
+			}
+			if res.SystemVersion != tt.system {
+				t.Errorf("system version = %q, want %q", res.SystemVersion, tt.system)
+			}
+		})
+	}
+}
+
+func TestResolveAppNotFound(t *testing.T) {
+	_, err := Resolve(NewCatalog(nil), "root", nil)
+	if !errors.Is(err, ErrAppNotFound) {
+		t.Fatalf("Resolve err = %v, want %v", err, ErrAppNotFound)
+	}
+}
+
+func TestResolveTreeMarksCycles(t *testing.T) {
+	catalog := NewCatalog([]*models.ApplicationInfoFullData{
+		app(version("root", "1.0.0", dep("a", ""))),
+		app(version("a", "1.0.0", dep("root", ""))),
+	})
+
+	res, err := Resolve(catalog, "root", nil)
+	if err != nil {
+		t.Fatalf("Resolve err: %v", err)
+	}
+
+	a := res.Tree.Dependencies[0]
+	if a.Name != "a" || a.Version != "1.0.0" || a.Cycle {
+		t.Fatalf("a = %+v", a)
+	}
+	back := a.Dependencies[0]
+	if back.Name != "root" || !back.Cycle || back.Dependencies != nil {
+		t.Fatalf("root below a = %+v, want a cycle without dependencies", back)
+	}
+}
+
+func TestCheckCatalog(t *testing.T) {
+	catalog := NewCatalog([]*models.ApplicationInfoFullData{
+		app(version("a", "1.0.0", dep("b", ""))),
+		app(version("b", "1.0.0", dep("x", ""))),
+	})
+
+	problems := CheckCatalog(catalog)
+	// a and b both reach the missing x, it is reported once
+	if len(problems) != 1 || problems[0].App != "b" || problems[0].Kind != models.DependencyProblemMissing {
+		t.Fatalf("problems = %+v", problems)
+	}
+}
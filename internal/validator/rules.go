@@ -0,0 +1,127 @@
+package validator
+
+import (
+	"app-store-server/pkg/models"
+	"fmt"
+	"regexp"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	DependencyTypeSystem      = "system"
+	DependencyTypeApplication = "application"
+)
+
+// appNamePattern is a DNS label, the name ends up in namespaces and chart names
+var appNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+func checkMetadataName(cfg *models.AppConfiguration, r *Reporter) {
+	name := cfg.Metadata.Name
+	if name == "" {
+		r.Errorf("metadata.name", "name is required")
+		return
+	}
+
+	if len(name) > 63 || !appNamePattern.MatchString(name) {
+		r.Warnf("metadata.name", "name %q is not a lowercase DNS label", name)
+	}
+}
+
+func checkMetadataVersion(cfg *models.AppConfiguration, r *Reporter) {
+	version := cfg.Metadata.Version
+	if version == "" {
+		r.Errorf("metadata.version", "version is required")
+		return
+	}
+
+	if _, err := semver.NewVersion(version); err != nil {
+		r.Errorf("metadata.version", "version %q is not a semantic version: %s", version, err.Error())
+		return
+	}
+
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		r.Warnf("metadata.version", "version %q is not a strict semantic version", version)
+	}
+}
+
+func checkMetadataFields(cfg *models.AppConfiguration, r *Reporter) {
+	if cfg.ConfigType == "" {
+		r.Warnf("olaresManifest.type", "type is empty")
+	}
+	if cfg.Metadata.Title == "" {
+		r.Warnf("metadata.title", "title is empty")
+	}
+	if cfg.Metadata.Icon == "" {
+		r.Warnf("metadata.icon", "icon is empty")
+	}
+	if len(cfg.Metadata.Categories) == 0 {
+		r.Warnf("metadata.categories", "no category")
+	}
+}
+
+func checkEntrances(cfg *models.AppConfiguration, r *Reporter) {
+	seen := make(map[string]int)
+	for i, entrance := range cfg.Entrances {
+		field := fmt.Sprintf("entrances[%d]", i)
+		if entrance.Name == "" {
+			r.Errorf(field+".name", "name is required")
+			continue
+		}
+
+		if first, ok := seen[entrance.Name]; ok {
+			r.Errorf(field+".name", "duplicate entrance name %q, first used by entrances[%d]", entrance.Name, first)
+			continue
+		}
+		seen[entrance.Name] = i
+
+		if entrance.Port <= 0 || entrance.Port > 65535 {
+			r.Warnf(field+".port", "port %d is out of range", entrance.Port)
+		}
+	}
+}
+
+func checkDependencies(cfg *models.AppConfiguration, r *Reporter) {
+	for i, dep := range cfg.Options.Dependencies {
+		field := fmt.Sprintf("options.dependencies[%d]", i)
+		if dep.Name == "" {
+			r.Errorf(field+".name", "name is required")
+		}
+
+		switch dep.Type {
+		case DependencyTypeSystem, DependencyTypeApplication:
+		default:
+			r.Warnf(field+".type", "unknown dependency type %q", dep.Type)
+		}
+
+		if dep.Version == "" {
+			// an empty olares constraint fails the version filter of the app list
+			if dep.Type == DependencyTypeSystem {
+				r.Errorf(field+".version", "version constraint is required")
+			}
+			continue
+		}
+
+		if _, err := semver.NewConstraint(dep.Version); err != nil {
+			r.Errorf(field+".version", "version constraint %q does not parse: %s", dep.Version, err.Error())
+		}
+	}
+}
+
+func checkPolicies(cfg *models.AppConfiguration, r *Reporter) {
+	entrances := make(map[string]bool)
+	for _, entrance := range cfg.Entrances {
+		entrances[entrance.Name] = true
+	}
+
+	for i, policy := range cfg.Options.Policies {
+		field := fmt.Sprintf("options.policies[%d]", i)
+		if _, err := regexp.Compile(policy.URIRegex); err != nil {
+			r.Errorf(field+".uriRegex", "uriRegex %q does not compile: %s", policy.URIRegex, err.Error())
+		}
+
+		if policy.EntranceName != "" && !entrances[policy.EntranceName] {
+			r.Warnf(field+".entranceName", "entrance %q does not exist", policy.EntranceName)
+		}
+	}
+}
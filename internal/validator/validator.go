@@ -0,0 +1,109 @@
+package validator
+
+import (
+	"app-store-server/pkg/models"
+	"fmt"
+	"time"
+)
+
+// RuleManifestSyntax reports a manifest that cannot be parsed or rendered at all.
+const RuleManifestSyntax = "manifest-syntax"
+
+// Rule checks one aspect of a manifest and reports what it finds to r.
+type Rule struct {
+	Name  string
+	Check func(cfg *models.AppConfiguration, r *Reporter)
+}
+
+// Reporter collects the issues of the rule being run.
+type Reporter struct {
+	rule   string
+	issues []models.ValidationIssue
+}
+
+func (r *Reporter) Errorf(field, format string, args ...any) {
+	r.add(models.ValidationError, field, fmt.Sprintf(format, args...))
+}
+
+func (r *Reporter) Warnf(field, format string, args ...any) {
+	r.add(models.ValidationWarning, field, fmt.Sprintf(format, args...))
+}
+
+func (r *Reporter) add(severity, field, message string) {
+	r.issues = append(r.issues, models.ValidationIssue{
+		Rule:     r.rule,
+		Severity: severity,
+		Field:    field,
+		Message:  message,
+	})
+}
+
+var rules = []Rule{
+	{Name: "metadata-name", Check: checkMetadataName},
+	{Name: "metadata-version", Check: checkMetadataVersion},
+	{Name: "metadata-fields", Check: checkMetadataFields},
+	{Name: "entrances", Check: checkEntrances},
+	{Name: "dependencies", Check: checkDependencies},
+	{Name: "policies", Check: checkPolicies},
+}
+
+// Rules returns the names of the rules Validate runs.
+func Rules() []string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+
+	return names
+}
+
+// Validate runs every rule on cfg and returns the issues found, errors and
+// warnings in rule order.
+func Validate(cfg *models.AppConfiguration) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+	for _, rule := range rules {
+		r := &Reporter{rule: rule.Name}
+		rule.Check(cfg, r)
+		issues = append(issues, r.issues...)
+	}
+
+	return issues
+}
+
+// SyntaxIssue reports a manifest that failed to parse.
+func SyntaxIssue(err error) models.ValidationIssue {
+	return models.ValidationIssue{
+		Rule:     RuleManifestSyntax,
+		Severity: models.ValidationError,
+		Message:  err.Error(),
+	}
+}
+
+// NewReport sorts the issues of an app into a report. Issues found more than
+// once, e.g. in both renderings of a templated manifest, are kept once.
+func NewReport(name, source string, issues []models.ValidationIssue) *models.ValidationReport {
+	report := &models.ValidationReport{
+		Name:     name,
+		Source:   source,
+		Time:     time.Now().Unix(),
+		Errors:   []models.ValidationIssue{},
+		Warnings: []models.ValidationIssue{},
+	}
+
+	seen := make(map[models.ValidationIssue]bool)
+	for _, issue := range issues {
+		if seen[issue] {
+			continue
+		}
+		seen[issue] = true
+
+		if issue.Severity == models.ValidationError {
+			report.Errors = append(report.Errors, issue)
+		} else {
+			report.Warnings = append(report.Warnings, issue)
+		}
+	}
+	report.Valid = len(report.Errors) == 0
+
+	return report
+}
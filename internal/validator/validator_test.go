@@ -0,0 +1,175 @@
+package validator
+
+import (
+	"app-store-server/pkg/models"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// validConfig returns a manifest every rule accepts.
+func validConfig() *models.AppConfiguration {
+	return &models.AppConfiguration{
+		ConfigType: "app",
+		Metadata: models.AppMetaData{
+			Name:       "notes",
+			Title:      "Notes",
+			Icon:       "https://example.com/notes.png",
+			Version:    "1.0.0",
+			Categories: []string{"Productivity"},
+		},
+		Entrances: []models.Entrance{{Name: "web", Port: 8080}},
+		Options: models.Options{
+			Policies: []models.Policy{{EntranceName: "web", URIRegex: `^/api/.*`}},
+			Dependencies: []models.Dependency{
+				{Name: "olares", Type: DependencyTypeSystem, Version: ">=1.10.0-0"},
+				{Name: "db", Type: DependencyTypeApplication},
+			},
+		},
+	}
+}
+
+// found lists the issues as rule:severity:field.
+func found(issues []models.ValidationIssue) []string {
+	var list []string
+	for _, issue := range issues {
+		list = append(list, issue.Rule+":"+issue.Severity+":"+issue.Field)
+	}
+
+	return list
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *models.AppConfiguration)
+		issues []string
+	}{
+		{
+			name:   "valid manifest",
+			modify: func(cfg *models.AppConfiguration) {},
+		},
+		{
+			name:   "missing name",
+			modify: func(cfg *models.AppConfiguration) { cfg.Metadata.Name = "" },
+			issues: []string{"metadata-name:error:metadata.name"},
+		},
+		{
+			name:   "name that is not a DNS label",
+			modify: func(cfg *models.AppConfiguration) { cfg.Metadata.Name = "My_App" },
+			issues: []string{"metadata-name:warning:metadata.name"},
+		},
+		{
+			name:   "missing version",
+			modify: func(cfg *models.AppConfiguration) { cfg.Metadata.Version = "" },
+			issues: []string{"metadata-version:error:metadata.version"},
+		},
+		{
+			name:   "version that does not parse",
+			modify: func(cfg *models.AppConfiguration) { cfg.Metadata.Version = "one" },
+			issues: []string{"metadata-version:error:metadata.version"},
+		},
+		{
+			name:   "loose version",
+			modify: func(cfg *models.AppConfiguration) { cfg.Metadata.Version = "v1.0" },
+			issues: []string{"metadata-version:warning:metadata.version"},
+		},
+		{
+			name: "empty display fields",
+			modify: func(cfg *models.AppConfiguration) {
+				cfg.ConfigType = ""
+				cfg.Metadata.Title = ""
+				cfg.Metadata.Icon = ""
+				cfg.Metadata.Categories = nil
+			},
+			issues: []string{
+				"metadata-fields:warning:olaresManifest.type",
+				"metadata-fields:warning:metadata.title",
+				"metadata-fields:warning:metadata.icon",
+				"metadata-fields:warning:metadata.categories",
+			},
+		},
+		{
+			name: "entrances",
+			modify: func(cfg *models.AppConfiguration) {
+				cfg.Entrances = append(cfg.Entrances,
+					models.Entrance{Port: 80},
+					models.Entrance{Name: "web", Port: 81},
+					models.Entrance{Name: "admin", Port: 70000},
+				)
+			},
+			issues: []string{
+				"entrances:error:entrances[1].name",
+				"entrances:error:entrances[2].name",
+				"entrances:warning:entrances[3].port",
+			},
+		},
+		{
+			name: "dependencies",
+			modify: func(cfg *models.AppConfiguration) {
+				cfg.Options.Dependencies = []models.Dependency{
+					{Type: DependencyTypeApplication},
+					{Name: "olares", Type: DependencyTypeSystem},
+					{Name: "db", Type: "service"},
+					{Name: "cache", Type: DependencyTypeApplication, Version: ">= one"},
+				}
+			},
+			issues: []string{
+				"dependencies:error:options.dependencies[0].name",
+				"dependencies:error:options.dependencies[1].version",
+				"dependencies:warning:options.dependencies[2].type",
+				"dependencies:error:options.dependencies[3].version",
+			},
+		},
+		{
+			name: "invalid uriRegex",
+			modify: func(cfg *models.AppConfiguration) {
+				cfg.Options.Policies = []models.Policy{{EntranceName: "web", URIRegex: `^/api/(`}}
+			},
+			issues: []string{"policies:error:options.policies[0].uriRegex"},
+		},
+		{
+			name: "policy of a missing entrance",
+			modify: func(cfg *models.AppConfiguration) {
+				cfg.Options.Policies = []models.Policy{{EntranceName: "admin", URIRegex: `.*`}}
+			},
+			issues: []string{"policies:warning:options.policies[0].entranceName"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			if got := found(Validate(cfg)); !reflect.DeepEqual(got, tt.issues) {
+				t.Errorf("issues = %v, want %v", got, tt.issues)
+			}
+		})
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	cfg := validConfig()
+	cfg.Metadata.Title = ""
+	cfg.Options.Policies[0].URIRegex = `[`
+
+	// a templated manifest is validated once per rendering
+	issues := append(Validate(cfg), Validate(cfg)...)
+	issues = append(issues, SyntaxIssue(errors.New("bad yaml")))
+
+	report := NewReport("notes", "main", issues)
+	if report.Valid {
+		t.Errorf("report with errors is valid")
+	}
+	if got, want := found(report.Errors), []string{"policies:error:options.policies[0].uriRegex", "manifest-syntax:error:"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+	if got, want := found(report.Warnings), []string{"metadata-fields:warning:metadata.title"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+
+	if report := NewReport("notes", "main", Validate(validConfig())); !report.Valid || len(report.Warnings) != 0 {
+		t.Errorf("report of a valid manifest = %+v", report)
+	}
+}
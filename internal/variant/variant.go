@@ -0,0 +1,151 @@
+package variant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// RenderVariantsEnv holds a JSON list of the contexts a templated manifest
+	// is rendered with, each a name and the map exposed as .Values, e.g.
+	// [{"name":"admin","values":{"admin":"admin","bfl":{"username":"admin"}}},
+	//  {"name":"user","values":{"admin":"admin","bfl":{"username":"user"}}},
+	//  {"name":"nvidia","values":{"admin":"admin","bfl":{"username":"admin"},"GPU":{"Type":"nvidia"}}}]
+	// The first context is the primary one, the app info is its rendering and
+	// the others are stored as variants. When it is not set the manifest is
+	// rendered for an admin and a regular user.
+	RenderVariantsEnv = "RENDER_VARIANTS"
+
+	Admin = "admin"
+	User  = "user"
+)
+
+var nameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9_-]*[a-z0-9])?$`)
+
+// Context is a named set of values a templated manifest is rendered with.
+type Context struct {
+	Name   string                 `json:"name"`
+	Values map[string]interface{} `json:"values"`
+}
+
+var contexts = defaultContexts()
+
+func defaultContexts() []Context {
+	return []Context{
+		{Name: Admin, Values: roleValues("admin")},
+		{Name: User, Values: roleValues("user")},
+	}
+}
+
+func roleValues(username string) map[string]interface{} {
+	return map[string]interface{}{
+		"admin": "admin",
+		"bfl": map[string]interface{}{
+			"username": username,
+		},
+	}
+}
+
+// Init loads the render contexts from RENDER_VARIANTS.
+func Init() error {
+	list, err := parseContexts(os.Getenv(RenderVariantsEnv))
+	if err != nil {
+		return err
+	}
+
+	contexts = list
+	for _, c := range contexts {
+		glog.Infof("render variant %s: %v", c.Name, c.Values)
+	}
+
+	return nil
+}
+
+func parseContexts(config string) ([]Context, error) {
+	if strings.TrimSpace(config) == "" {
+		return defaultContexts(), nil
+	}
+
+	var list []Context
+	if err := json.Unmarshal([]byte(config), &list); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", RenderVariantsEnv, err)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("invalid %s: no variant", RenderVariantsEnv)
+	}
+
+	names := make(map[string]bool)
+	for i, c := range list {
+		if !nameRegex.MatchString(c.Name) {
+			return nil, fmt.Errorf("invalid %s: bad variant name %q", RenderVariantsEnv, c.Name)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("invalid %s: duplicate variant name %q", RenderVariantsEnv, c.Name)
+		}
+		names[c.Name] = true
+
+		if c.Values == nil {
+			list[i].Values = map[string]interface{}{}
+		}
+	}
+
+	return list, nil
+}
+
+// Contexts returns copies of the render contexts, the primary one first.
+// Renders run concurrently and templates may change .Values, e.g. with sprig
+// set, so each render gets values of its own.
+func Contexts() []Context {
+	list := make([]Context, len(contexts))
+	for i, c := range contexts {
+		list[i] = c.clone()
+	}
+
+	return list
+}
+
+// Primary returns a copy of the context the app info itself is rendered with.
+func Primary() Context {
+	return contexts[0].clone()
+}
+
+func (c Context) clone() Context {
+	values, _ := copyValue(c.Values).(map[string]interface{})
+	return Context{Name: c.Name, Values: values}
+}
+
+// copyValue deep copies the maps and lists of values decoded from JSON.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = copyValue(e)
+		}
+		return l
+	}
+
+	return v
+}
+
+// Exists reports whether name is a configured render context.
+func Exists(name string) bool {
+	for _, c := range contexts {
+		if c.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
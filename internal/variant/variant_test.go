@@ -0,0 +1,85 @@
+package variant
+
+import (
+	"reflect"
+	"testing"
+)
+
+func names(list []Context) []string {
+	var result []string
+	for _, c := range list {
+		result = append(result, c.Name)
+	}
+
+	return result
+}
+
+func TestParseContexts(t *testing.T) {
+	list, err := parseContexts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(list); !reflect.DeepEqual(got, []string{Admin, User}) {
+		t.Errorf("default contexts = %v", got)
+	}
+
+	list, err = parseContexts(`[
+		{"name":"admin","values":{"bfl":{"username":"admin"}}},
+		{"name":"nvidia","values":{"GPU":{"Type":"nvidia"}}},
+		{"name":"bare"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(list); !reflect.DeepEqual(got, []string{"admin", "nvidia", "bare"}) {
+		t.Errorf("contexts = %v", got)
+	}
+	if list[2].Values == nil {
+		t.Error("context without values has nil values")
+	}
+}
+
+func TestParseContextsErrors(t *testing.T) {
+	tests := map[string]string{
+		"not json":       `{`,
+		"empty list":     `[]`,
+		"bad name":       `[{"name":"GPU Nvidia"}]`,
+		"duplicate name": `[{"name":"admin"},{"name":"admin"}]`,
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseContexts(config); err == nil {
+				t.Errorf("no error for %s", config)
+			}
+		})
+	}
+}
+
+func TestContextsAreCopies(t *testing.T) {
+	saved := contexts
+	t.Cleanup(func() { contexts = saved })
+
+	var err error
+	contexts, err = parseContexts(`[{"name":"admin","values":{"bfl":{"username":"admin"},"tags":["a"]}},{"name":"user"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a template changing its values must not change the next render
+	c := Contexts()[0]
+	c.Values["bfl"].(map[string]interface{})["username"] = "changed"
+	c.Values["tags"].([]interface{})[0] = "changed"
+
+	want := map[string]interface{}{
+		"bfl":  map[string]interface{}{"username": "admin"},
+		"tags": []interface{}{"a"},
+	}
+	if got := Primary(); got.Name != "admin" || !reflect.DeepEqual(got.Values, want) {
+		t.Errorf("primary = %+v, want values %v", got, want)
+	}
+
+	if !Exists("user") || Exists("nvidia") {
+		t.Error("Exists does not match the configured contexts")
+	}
+}
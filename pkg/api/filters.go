@@ -17,9 +17,13 @@ package api
 import (
 	"app-store-server/pkg/utils"
 	"bytes"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/emicklei/go-restful/v3"
@@ -69,3 +73,49 @@ func LogRequestAndResponse(req *restful.Request, resp *restful.Response, chain *
 		time.Since(start)/time.Millisecond,
 	)
 }
+
+const (
+	AdminTokenEnv    = "ADMIN_TOKEN"
+	AdminTokenHeader = "X-Admin-Token"
+)
+
+// AdminAuth guards the admin routes with the token from ADMIN_TOKEN, sent as
+// X-Admin-Token or as a bearer token. Without a configured token the admin
+// routes are disabled.
+func AdminAuth(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	expected := os.Getenv(AdminTokenEnv)
+	if expected == "" {
+		HandleForbidden(resp, req, errors.New("admin api is disabled, ADMIN_TOKEN is not set"))
+		return
+	}
+
+	token := req.HeaderParameter(AdminTokenHeader)
+	if token == "" {
+		token = strings.TrimPrefix(req.HeaderParameter("Authorization"), "Bearer ")
+	}
+
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		HandleUnauthorized(resp, req, errors.New("invalid admin token"))
+		return
+	}
+
+	chain.ProcessFilter(req, resp)
+}
+
+const (
+	InstallerTokenEnv    = "INSTALLER_TOKEN"
+	InstallerTokenHeader = "X-Installer-Token"
+)
+
+// IsInstaller reports whether the request carries the installer token from
+// INSTALLER_TOKEN in X-Installer-Token. Without a configured token no request is.
+func IsInstaller(req *restful.Request) bool {
+	expected := os.Getenv(InstallerTokenEnv)
+	if expected == "" {
+		return false
+	}
+
+	token := req.HeaderParameter(InstallerTokenHeader)
+
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"app-store-server/pkg/redact"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// Project returns the view of v for the client of the request: installers
+// get it as it is, the other clients without the sensitive fields when
+// redaction is enabled.
+func Project[T any](req *restful.Request, v T) T {
+	if !redact.Enabled() || IsInstaller(req) {
+		return v
+	}
+
+	return redact.Redact(v)
+}
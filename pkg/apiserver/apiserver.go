@@ -19,7 +19,9 @@ import (
 	"app-store-server/internal/constants"
 	"app-store-server/internal/es"
 	"app-store-server/internal/gitapp"
+	"app-store-server/internal/leader"
 	"app-store-server/internal/mongo"
+	"app-store-server/internal/variant"
 	"app-store-server/pkg/api"
 	servicev1 "app-store-server/pkg/apiserver/service/v1"
 	servicev2 "app-store-server/pkg/apiserver/service/v2"
@@ -77,11 +79,21 @@ func initMiddlewares() {
 		glog.Fatalln(err)
 	}
 
+	err = leader.Init()
+	if err != nil {
+		glog.Fatalln(err)
+	}
+
 	err = es.Init()
 	if err != nil {
 		glog.Fatalln(err)
 	}
 
+	err = variant.Init()
+	if err != nil {
+		glog.Fatalln(err)
+	}
+
 	err = gitapp.Init()
 	if err != nil {
 		glog.Fatalln(err)
@@ -1,16 +1,19 @@
 package v1
 
 import (
-	"app-store-server/internal/app"
 	"app-store-server/internal/constants"
 	"app-store-server/internal/es"
+	"app-store-server/internal/i18n"
 	"app-store-server/internal/mongo"
+	"app-store-server/internal/variant"
 	"app-store-server/pkg/models"
 	"app-store-server/pkg/utils"
 	"fmt"
 	"path"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/emicklei/go-restful/v3"
 	"github.com/golang/glog"
 )
 
@@ -21,7 +24,10 @@ func getChartPath(appName string, version string) string {
 	}
 
 	//not a chart name, search chart name
-	info, err := getInfoByName(appName)
+	info, err := getInfoByName(appName, false)
+	if err != nil || info == nil {
+		return ""
+	}
 	app, err := filterVersionForApp(info, version)
 
 	if err == nil && app.ChartName != "" {
@@ -39,25 +45,44 @@ func getChartPathNoVersion(fileName string) string {
 	return path.Join(constants.AppGitZipLocalDir, fileName)
 }
 
-func getInfoByName(appName string) (*models.ApplicationInfoFullData, error) {
-	info, err := es.SearchByNameAccurate(appName)
-	if err == nil && info != nil {
-		return info, nil
+// getInfoByName gets a published app from the search index, or from mongo when
+// the sensitive fields are needed, the index does not hold them. It returns
+// nil when the app is not published, e.g. its manifest failed validation.
+func getInfoByName(appName string, sensitive bool) (*models.ApplicationInfoFullData, error) {
+	if !sensitive {
+		info, err := es.SearchByNameAccurate(appName)
+		if err == nil && info != nil {
+			return info, nil
+		}
 	}
 
-	info, err = mongo.GetAppInfoByName(appName)
-	if err == nil && info != nil {
-		return info, nil
+	return mongo.GetAppInfoByName(appName)
+}
+
+// withSensitiveFields replaces the apps found in the search index with their
+// mongo documents, keeping the order.
+func withSensitiveFields(apps []*models.ApplicationInfoFullData) ([]*models.ApplicationInfoFullData, error) {
+	if len(apps) == 0 {
+		return apps, nil
 	}
 
-	latest, err := app.ReadAppInfo(appName)
+	names := make([]string, 0, len(apps))
+	for _, a := range apps {
+		names = append(names, a.Name)
+	}
 
-	info = &models.ApplicationInfoFullData{}
-	info.History["latest"] = *latest
-	info.Name = latest.Name
-	info.AppLabels = latest.AppLabels
+	infos, err := mongo.GetAppInfos(names)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, a := range apps {
+		if info, ok := infos[a.Name]; ok {
+			apps[i] = info
+		}
+	}
 
-	return info, err
+	return apps, nil
 }
 
 func pickVersionForAppsWithMap(apps map[string]*models.ApplicationInfoFullData, version string) (map[string]*models.ApplicationInfoEntry, error) {
@@ -279,3 +304,94 @@ func filterVersionForApp(app *models.ApplicationInfoFullData, version string) (m
 
 	return result, fmt.Errorf("no matching version found")
 }
+
+// getVariantParam returns the render variant a request asks for, empty for the
+// app info with all its variants.
+func getVariantParam(req *restful.Request) (string, error) {
+	name := req.QueryParameter(ParamVariant)
+	if name != "" && !variant.Exists(name) {
+		return "", fmt.Errorf("unknown variant %q", name)
+	}
+
+	return name, nil
+}
+
+// selectVariant returns the app as rendered for the named context. The fields
+// the server sets after rendering are kept from the app info. An app without a
+// templated manifest is the same for every context.
+func selectVariant(entry models.ApplicationInfoEntry, name string) models.ApplicationInfoEntry {
+	if name == "" {
+		return entry
+	}
+
+	selected := entry
+	if v, ok := entry.Variants[name]; ok && name != variant.Primary().Name {
+		selected = v
+		selected.Id = entry.Id
+		selected.Name = entry.Name
+		selected.ChartName = entry.ChartName
+		selected.LastCommitHash = entry.LastCommitHash
+		selected.Source = entry.Source
+		selected.CreateTime = entry.CreateTime
+		selected.UpdateTime = entry.UpdateTime
+		selected.AppLabels = entry.AppLabels
+		selected.Labels = entry.Labels
+		selected.Count = entry.Count
+		selected.I18n = entry.I18n
+	}
+	selected.Variants = nil
+
+	return selected
+}
+
+func selectVariantForApps(entries []models.ApplicationInfoEntry, name string) []models.ApplicationInfoEntry {
+	if name == "" {
+		return entries
+	}
+
+	for i := range entries {
+		entries[i] = selectVariant(entries[i], name)
+	}
+
+	return entries
+}
+
+// splitList returns the items of a comma separated query parameter.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// getLanguages returns the languages a request asks for, the lang query
+// parameter, a comma separated list, over the Accept-Language header.
+func getLanguages(req *restful.Request) []string {
+	if lang := req.QueryParameter(ParamLang); lang != "" {
+		return splitList(lang)
+	}
+
+	return i18n.ParseAcceptLanguage(req.HeaderParameter("Accept-Language"))
+}
+
+// localizeApp resolves an app in the languages of the request and leaves out
+// its I18n map when the request asks to.
+func localizeApp(req *restful.Request, entry models.ApplicationInfoEntry) models.ApplicationInfoEntry {
+	i18n.Localize(&entry, getLanguages(req), req.QueryParameter(ParamI18n) == "false")
+
+	return entry
+}
+
+func localizeApps(req *restful.Request, entries []models.ApplicationInfoEntry) []models.ApplicationInfoEntry {
+	langs := getLanguages(req)
+	omitMap := req.QueryParameter(ParamI18n) == "false"
+	for i := range entries {
+		i18n.Localize(&entries[i], langs, omitMap)
+	}
+
+	return entries
+}
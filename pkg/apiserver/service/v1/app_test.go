@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"app-store-server/internal/variant"
+	"app-store-server/pkg/models"
+	"testing"
+)
+
+func TestSelectVariant(t *testing.T) {
+	entry := models.ApplicationInfoEntry{
+		Id:        "id",
+		Name:      "notes",
+		Title:     "Notes for admins",
+		ChartName: "notes-1.0.0.tgz",
+		Source:    "default",
+		Variants: map[string]models.ApplicationInfoEntry{
+			variant.User: {Name: "notes", Title: "Notes", ChartName: "other.tgz"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		title string
+	}{
+		{name: variant.User, title: "Notes"},
+		{name: variant.Admin, title: "Notes for admins"},
+		{name: "missing", title: "Notes for admins"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectVariant(entry, tt.name)
+			if got.Title != tt.title {
+				t.Errorf("title = %q, want %q", got.Title, tt.title)
+			}
+			// the identity of the app is the one of the stored entry
+			if got.Id != "id" || got.ChartName != "notes-1.0.0.tgz" || got.Source != "default" {
+				t.Errorf("selected = %+v, want the identity of the entry", got)
+			}
+			if got.Variants != nil {
+				t.Errorf("selected variant keeps the variants")
+			}
+		})
+	}
+
+	if got := selectVariant(entry, ""); len(got.Variants) != 1 {
+		t.Errorf("no variant asked, variants = %v", got.Variants)
+	}
+}
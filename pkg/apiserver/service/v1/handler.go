@@ -57,7 +57,16 @@ func (h *Handler) handleList(req *restful.Request, resp *restful.Response) {
 
 	from, sizeN := utils.VerifyFromAndSize(page, size)
 
-	appList, count, err := mongo.GetAppLists(int64(from), int64(sizeN), category, ty)
+	variantName, err := getVariantParam(req)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	labels := splitList(req.QueryParameter("labels"))
+	excludedLabels := splitList(req.QueryParameter("excludedLabels"))
+	middleware := splitList(req.QueryParameter(ParamMiddleware))
+	appList, count, err := mongo.GetAppListsWithLabels(int64(from), int64(sizeN), category, ty, labels, excludedLabels, middleware)
 	if err != nil {
 		api.HandleError(resp, req, err)
 		return
@@ -69,7 +78,14 @@ func (h *Handler) handleList(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResultWithCount(appEntryList, count)))
+	policies, err := mongo.GetCategoryPolicies()
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+	appEntryList, _ = applyCategoryPolicies(appEntryList, policies)
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResultWithCount(api.Project(req, localizeApps(req, selectVariantForApps(appEntryList, variantName))), count)))
 }
 
 func (h *Handler) handleTypes(req *restful.Request, resp *restful.Response) {
@@ -159,39 +175,44 @@ func (h *Handler) handleAppInfo(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	info, err := getInfoByName(appName)
+	variantName, err := getVariantParam(req)
 	if err != nil {
-		api.HandleError(resp, req, err)
+		api.HandleBadRequest(resp, req, err)
 		return
 	}
 
-	appEntry, err := filterVersionForApp(info, version)
+	info, err := getInfoByName(appName, api.IsInstaller(req))
 	if err != nil {
 		api.HandleError(resp, req, err)
 		return
 	}
+	if info == nil {
+		api.HandleNotFound(resp, req, fmt.Errorf("%s not found", appName))
+		return
+	}
 
-	resp.WriteEntity(models.NewResponse(api.OK, api.Success, appEntry))
-}
-
-func (h *Handler) handleReadme(req *restful.Request, resp *restful.Response) {
-	appName := req.PathParameter(ParamAppName)
-	if appName == "" {
-		api.HandleError(resp, req, errors.New("empty app name"))
+	appEntry, err := filterVersionForApp(info, version)
+	if err != nil {
+		api.HandleError(resp, req, err)
 		return
 	}
 
-	content, err := gitapp.ReadMe(appName)
+	policies, err := mongo.GetCategoryPolicies()
 	if err != nil {
 		api.HandleError(resp, req, err)
 		return
 	}
+	if categoryHidden(appEntry, policies) {
+		api.HandleNotFound(resp, req, fmt.Errorf("%s not found", appName))
+		return
+	}
+	appEntry = labelCategory(appEntry, policies)
 
-	resp.Write(content)
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, api.Project(req, localizeApp(req, selectVariant(appEntry, variantName)))))
 }
 
 func (h *Handler) handleUpdate(req *restful.Request, resp *restful.Response) {
-	err := app.GitPullAndUpdate(true)
+	err := app.GitPullAndUpdate(true, models.SyncTriggerManual)
 
 	if err != nil {
 		api.HandleError(resp, req, err)
@@ -221,8 +242,16 @@ func (h *Handler) handleTop(req *restful.Request, resp *restful.Response) {
 	}
 
 	excludedLabelsSlice := strings.Split(excludedLabels, ",")
+
+	variantName, err := getVariantParam(req)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
 	sizeN := utils.VerifyTopSize(size)
-	infos, err := mongo.GetTopApplicationInfos(category, ty, excludedLabelsSlice, sizeN)
+	middleware := splitList(req.QueryParameter(ParamMiddleware))
+	infos, err := mongo.GetTopApplicationInfos(category, ty, excludedLabelsSlice, middleware, sizeN)
 	if err != nil {
 		api.HandleError(resp, req, err)
 		return
@@ -239,7 +268,14 @@ func (h *Handler) handleTop(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResult(appEntryList)))
+	policies, err := mongo.GetCategoryPolicies()
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+	appEntryList, _ = applyCategoryPolicies(appEntryList, policies)
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResult(api.Project(req, localizeApps(req, selectVariantForApps(appEntryList, variantName))))))
 }
 
 func (h *Handler) handleSearch(req *restful.Request, resp *restful.Response) {
@@ -260,19 +296,43 @@ func (h *Handler) handleSearch(req *restful.Request, resp *restful.Response) {
 	}
 
 	from, sizeN := utils.VerifyFromAndSize(page, size)
-	appList, count, err := es.SearchByNameWildcard(from, sizeN, appName)
+
+	variantName, err := getVariantParam(req)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	policies, err := mongo.GetCategoryPolicies()
 	if err != nil {
 		api.HandleError(resp, req, err)
 		return
 	}
 
+	appList, count, err := es.SearchByNameWildcard(from, sizeN, appName, mongo.HiddenCategories(policies, nil))
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	if api.IsInstaller(req) {
+		appList, err = withSensitiveFields(appList)
+		if err != nil {
+			api.HandleError(resp, req, err)
+			return
+		}
+	}
+
 	appEntryList, err := pickVersionForApps(appList, version)
 	if err != nil {
 		api.HandleError(resp, req, err)
 		return
 	}
 
-	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResultWithCount(appEntryList, count)))
+	// the hidden categories are left out by the query, the policies still label the apps
+	appEntryList, _ = applyCategoryPolicies(appEntryList, policies)
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResultWithCount(api.Project(req, localizeApps(req, selectVariantForApps(appEntryList, variantName))), count)))
 }
 
 func (h *Handler) handleExist(req *restful.Request, resp *restful.Response) {
@@ -310,8 +370,14 @@ func (h *Handler) handleInfos(req *restful.Request, resp *restful.Response) {
 		version = os.Getenv("LATEST_VERSION")
 	}
 
+	variantName, err := getVariantParam(req)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
 	var names []string
-	err := req.ReadEntity(&names)
+	err = req.ReadEntity(&names)
 	if err != nil {
 		api.HandleError(resp, req, err)
 		return
@@ -323,6 +389,23 @@ func (h *Handler) handleInfos(req *restful.Request, resp *restful.Response) {
 		return
 	}
 	appEntryList, err := pickVersionForAppsWithMap(mapInfo, version)
+	if variantName != "" {
+		for name, entry := range appEntryList {
+			selected := selectVariant(*entry, variantName)
+			appEntryList[name] = &selected
+		}
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, api.Project(req, appEntryList)))
+}
+
+// handleMiddlewares counts the apps requesting each kind of middleware.
+func (h *Handler) handleMiddlewares(req *restful.Request, resp *restful.Response) {
+	counts, err := mongo.CountAppsByMiddleware()
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
 
-	resp.WriteEntity(models.NewResponse(api.OK, api.Success, appEntryList))
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResult(counts)))
 }
@@ -1,8 +1,10 @@
 package v1
 
 import (
+	"app-store-server/internal/app"
 	"app-store-server/internal/appadmin"
 	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
 	"errors"
 	"os"
 
@@ -47,14 +49,22 @@ func (h *Handler) handleVersionHistory(req *restful.Request, resp *restful.Respo
 		return
 	}
 
-	respBody, err := appadmin.GetAppHistory(appName)
-	if err != nil {
+	history, err := app.GetAppVersionHistory(appName)
+	if err == nil {
+		resp.WriteEntity(models.NewResponse(api.OK, api.Success, history))
+		return
+	}
+	if !appadmin.GitBotConfigured() {
 		api.HandleError(resp, req, err)
 		return
 	}
+	glog.Warningf("GetAppVersionHistory %s err:%s, fall back to git-bot", appName, err.Error())
 
-	_, err = resp.Write([]byte(respBody))
+	history, err = appadmin.GetAppVersionHistory(appName)
 	if err != nil {
-		glog.Warningf("err:%s", err)
+		api.HandleError(resp, req, err)
+		return
 	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, history))
 }
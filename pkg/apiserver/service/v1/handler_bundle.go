@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"app-store-server/internal/app"
+	"app-store-server/internal/gitapp"
+	"app-store-server/internal/leader"
+	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
+	"errors"
+	"fmt"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+func (h *Handler) handleUploadBundle(req *restful.Request, resp *restful.Response) {
+	source := req.QueryParameter("source")
+	if source == "" {
+		source = gitapp.DefaultSourceName
+	}
+
+	// the bundle is unpacked on the disk of the replica, only the leader ingests it
+	if !leader.IsLeader() {
+		holder, _ := leader.GetLeader()
+		api.HandleConflict(resp, req, fmt.Errorf("replica %s is not the leader, upload the bundle to the leader %q", leader.Identity(), holder))
+		return
+	}
+
+	digest, err := gitapp.SaveBundle(source, req.Request.Body)
+	if err != nil {
+		if errors.Is(err, gitapp.ErrSourceNotFound) {
+			api.HandleNotFound(resp, req, err)
+			return
+		}
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	err = app.RequestGitPullAndUpdate(models.SyncTriggerAdmin)
+	if err != nil {
+		api.HandleError(resp, req, fmt.Errorf("request sync: %w", err))
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, &models.BundleRes{
+		Source: source,
+		Digest: digest,
+	}))
+}
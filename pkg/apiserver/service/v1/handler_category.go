@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"app-store-server/internal/constants"
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+const ParamCategory = "category"
+
+func (h *Handler) handleCategoryPolicies(req *restful.Request, resp *restful.Response) {
+	policies, err := mongo.GetCategoryPolicies()
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+	if policies == nil {
+		policies = []*models.CategoryPolicy{}
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResultWithCount(policies, int64(len(policies)))))
+}
+
+func (h *Handler) handleSetCategoryPolicy(req *restful.Request, resp *restful.Response) {
+	policy := &models.CategoryPolicy{}
+	err := req.ReadEntity(policy)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	policy.Category = strings.TrimSpace(policy.Category)
+	if policy.Category == "" {
+		api.HandleBadRequest(resp, req, errors.New("category is required"))
+		return
+	}
+	switch policy.Action {
+	case models.CategoryActionHide:
+		policy.Label = ""
+	case models.CategoryActionLabel:
+		if policy.Label == "" {
+			policy.Label = constants.DisableLabel
+		}
+	default:
+		api.HandleBadRequest(resp, req, fmt.Errorf("unknown action %q, use %s or %s", policy.Action, models.CategoryActionHide, models.CategoryActionLabel))
+		return
+	}
+	policy.UpdateTime = time.Now().Unix()
+
+	err = mongo.UpsertCategoryPolicy(policy)
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, policy))
+}
+
+func (h *Handler) handleDeleteCategoryPolicy(req *restful.Request, resp *restful.Response) {
+	category := req.PathParameter(ParamCategory)
+
+	found, err := mongo.DeleteCategoryPolicy(category)
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+	if !found {
+		api.HandleNotFound(resp, req, fmt.Errorf("no policy for category %s", category))
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, nil))
+}
+
+// applyCategoryPolicies leaves out the apps of hidden categories and adds the
+// labels of labeled categories. It returns the apps kept and how many were left out.
+func applyCategoryPolicies(entries []models.ApplicationInfoEntry, policies []*models.CategoryPolicy) ([]models.ApplicationInfoEntry, int) {
+	if len(policies) == 0 {
+		return entries, 0
+	}
+
+	kept := entries[:0]
+	for _, entry := range entries {
+		if categoryHidden(entry, policies) {
+			continue
+		}
+		kept = append(kept, labelCategory(entry, policies))
+	}
+
+	return kept, len(entries) - len(kept)
+}
+
+func categoryHidden(entry models.ApplicationInfoEntry, policies []*models.CategoryPolicy) bool {
+	for _, p := range policies {
+		if p.Action == models.CategoryActionHide && p.Matches(entry.Categories) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func labelCategory(entry models.ApplicationInfoEntry, policies []*models.CategoryPolicy) models.ApplicationInfoEntry {
+	for _, p := range policies {
+		if p.Action != models.CategoryActionLabel || !p.Matches(entry.Categories) {
+			continue
+		}
+
+		has := false
+		for _, l := range entry.AppLabels {
+			if l == p.Label {
+				has = true
+				break
+			}
+		}
+		if !has {
+			// the labels are shared with the stored app, extend a copy
+			entry.AppLabels = append(append([]string{}, entry.AppLabels...), p.Label)
+		}
+	}
+
+	return entry
+}
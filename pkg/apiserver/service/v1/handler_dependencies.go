@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"app-store-server/internal/app"
+	"app-store-server/internal/mongo"
+	"app-store-server/internal/resolver"
+	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// handleDependencies resolves the apps an app depends on, with the versions
+// to install on the system version, so the installer gets them in one call.
+func (h *Handler) handleDependencies(req *restful.Request, resp *restful.Response) {
+	appName := req.PathParameter(ParamAppName)
+	version := req.QueryParameter("version")
+	if version == "" {
+		version = "1.10.9-0"
+	}
+
+	if version == "undefined" {
+		version = "1.10.9-0"
+	}
+
+	if version == "latest" {
+		version = os.Getenv("LATEST_VERSION")
+	}
+
+	if appName == "" {
+		api.HandleBadRequest(resp, req, errors.New("empty app name"))
+		return
+	}
+
+	res, err := app.ResolveDependencies(appName, version)
+	if errors.Is(err, resolver.ErrAppNotFound) || errors.Is(err, resolver.ErrNoVersion) {
+		api.HandleNotFound(resp, req, fmt.Errorf("%s: %w", appName, err))
+		return
+	}
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	info, err := mongo.GetAppInfoByName(appName)
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+	policies, err := mongo.GetCategoryPolicies()
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+	// a removed app can linger in the cached catalog
+	if info == nil || categoryHidden(info.History["latest"], policies) {
+		api.HandleNotFound(resp, req, fmt.Errorf("%s not found", appName))
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, res))
+}
+
+// handleInstallCheck tells whether the app can be installed, or upgraded to,
+// next to the apps installed on the client.
+func (h *Handler) handleInstallCheck(req *restful.Request, resp *restful.Response) {
+	appName := req.PathParameter(ParamAppName)
+	version := req.QueryParameter("version")
+	if version == "" {
+		version = "1.10.9-0"
+	}
+
+	if version == "undefined" {
+		version = "1.10.9-0"
+	}
+
+	if version == "latest" {
+		version = os.Getenv("LATEST_VERSION")
+	}
+
+	checkReq := &models.InstallCheckReq{}
+	err := req.ReadEntity(checkReq)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	res, err := app.CheckInstall(appName, checkReq.Version, version, checkReq.Installed)
+	if errors.Is(err, resolver.ErrAppNotFound) || errors.Is(err, resolver.ErrNoVersion) {
+		api.HandleNotFound(resp, req, fmt.Errorf("%s: %w", appName, err))
+		return
+	}
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, res))
+}
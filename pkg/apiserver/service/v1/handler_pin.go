@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"app-store-server/internal/app"
+	"app-store-server/internal/gitapp"
+	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
+	"errors"
+	"fmt"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+func (h *Handler) handlePinStatus(req *restful.Request, resp *restful.Response) {
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, gitapp.GetPinStatus()))
+}
+
+func (h *Handler) handlePin(req *restful.Request, resp *restful.Response) {
+	pinReq, err := readPinReq(req)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+	if pinReq.Ref == "" {
+		api.HandleBadRequest(resp, req, errors.New("ref is required"))
+		return
+	}
+
+	commit, err := gitapp.PinSource(pinReq.Source, pinReq.Ref)
+	if err != nil {
+		handlePinError(resp, req, err)
+		return
+	}
+
+	err = app.RequestGitPullAndUpdate(models.SyncTriggerAdmin)
+	if err != nil {
+		api.HandleError(resp, req, fmt.Errorf("request sync: %w", err))
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, &models.PinRes{
+		Source: pinReq.Source,
+		Ref:    pinReq.Ref,
+		Commit: commit,
+	}))
+}
+
+func (h *Handler) handleUnpin(req *restful.Request, resp *restful.Response) {
+	pinReq, err := readPinReq(req)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	err = gitapp.UnpinSource(pinReq.Source)
+	if err != nil {
+		handlePinError(resp, req, err)
+		return
+	}
+
+	err = app.RequestGitPullAndUpdate(models.SyncTriggerAdmin)
+	if err != nil {
+		api.HandleError(resp, req, fmt.Errorf("request sync: %w", err))
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, &models.PinRes{
+		Source: pinReq.Source,
+	}))
+}
+
+func (h *Handler) handleRollback(req *restful.Request, resp *restful.Response) {
+	pinReq, err := readPinReq(req)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	commit, err := gitapp.RollbackSource(pinReq.Source, pinReq.Ref)
+	if err != nil {
+		handlePinError(resp, req, err)
+		return
+	}
+
+	err = app.RequestGitPullAndUpdate(models.SyncTriggerAdmin)
+	if err != nil {
+		api.HandleError(resp, req, fmt.Errorf("request sync: %w", err))
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, &models.PinRes{
+		Source: pinReq.Source,
+		Ref:    pinReq.Ref,
+		Commit: commit,
+	}))
+}
+
+// readPinReq reads the request body, the source defaults to the default source.
+func readPinReq(req *restful.Request) (*models.PinReq, error) {
+	pinReq := &models.PinReq{}
+	if req.Request.ContentLength != 0 {
+		err := req.ReadEntity(pinReq)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if pinReq.Source == "" {
+		pinReq.Source = gitapp.DefaultSourceName
+	}
+
+	return pinReq, nil
+}
+
+func handlePinError(resp *restful.Response, req *restful.Request, err error) {
+	if errors.Is(err, gitapp.ErrSourceNotFound) {
+		api.HandleNotFound(resp, req, err)
+		return
+	}
+
+	api.HandleBadRequest(resp, req, err)
+}
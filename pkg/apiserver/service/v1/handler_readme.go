@@ -0,0 +1,149 @@
+package v1
+
+import (
+	"app-store-server/internal/gitapp"
+	"app-store-server/internal/mongo"
+	"app-store-server/internal/readme"
+	"app-store-server/pkg/api"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+const (
+	ParamAssetPath = "path"
+	ParamFormat    = "format"
+
+	readmeFormatHTML = "html"
+	// readmeCacheControl lets clients reuse a README for a while, the ETag
+	// revalidates it after a catalog update
+	readmeCacheControl = "public, max-age=300"
+)
+
+func (h *Handler) handleReadme(req *restful.Request, resp *restful.Response) {
+	appName := req.PathParameter(ParamAppName)
+	if appName == "" {
+		api.HandleError(resp, req, errors.New("empty app name"))
+		return
+	}
+
+	format := req.QueryParameter(ParamFormat)
+	if format != "" && format != readmeFormatHTML {
+		api.HandleBadRequest(resp, req, fmt.Errorf("unknown format %q", format))
+		return
+	}
+
+	if !h.publishedApp(req, resp, appName) {
+		return
+	}
+
+	file, err := gitapp.FindReadMe(appName, getLanguages(req))
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	content := readme.RewriteLinks(file.Content, file.Dir, func(p string) string {
+		return assetURL(appName, p)
+	})
+
+	contentType := "text/markdown; charset=utf-8"
+	if format == readmeFormatHTML {
+		content = readme.RenderHTML(content)
+		contentType = "text/html; charset=utf-8"
+	}
+
+	sum := sha256.Sum256(content)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	header := resp.ResponseWriter.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", readmeCacheControl)
+	header.Set("Vary", "Accept-Language")
+	if file.Lang != "" {
+		header.Set("Content-Language", file.Lang)
+	}
+
+	if etagMatches(req.HeaderParameter("If-None-Match"), etag) {
+		resp.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", contentType)
+	resp.Write(content)
+}
+
+func (h *Handler) handleAsset(req *restful.Request, resp *restful.Response) {
+	appName := req.PathParameter(ParamAppName)
+	assetPath := req.PathParameter(ParamAssetPath)
+
+	if !h.publishedApp(req, resp, appName) {
+		return
+	}
+
+	p, err := gitapp.FindAppAsset(appName, assetPath)
+	if errors.Is(err, gitapp.ErrAssetNotFound) {
+		api.HandleNotFound(resp, req, fmt.Errorf("%s of %s not found", assetPath, appName))
+		return
+	}
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	header := resp.ResponseWriter.Header()
+	header.Set("Cache-Control", readmeCacheControl)
+	// an svg may carry scripts, never run them on this origin
+	header.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	header.Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(resp.ResponseWriter, req.Request, p)
+}
+
+// publishedApp writes a not found response unless the app is published and
+// not in a hidden category, the same as the app info.
+func (h *Handler) publishedApp(req *restful.Request, resp *restful.Response, appName string) bool {
+	info, err := mongo.GetAppInfoByName(appName)
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return false
+	}
+	policies, err := mongo.GetCategoryPolicies()
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return false
+	}
+	if info == nil || categoryHidden(info.History["latest"], policies) {
+		api.HandleNotFound(resp, req, fmt.Errorf("%s not found", appName))
+		return false
+	}
+
+	return true
+}
+
+// assetURL returns the URL the asset route serves a file of an app directory at.
+func assetURL(appName, p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	return fmt.Sprintf("%s/%s/applications/%s/assets/%s", APIRootPath, Version, url.PathEscape(appName), strings.Join(segments, "/"))
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+
+	return false
+}
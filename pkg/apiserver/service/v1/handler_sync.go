@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"app-store-server/internal/app"
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
+	"app-store-server/pkg/utils"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+func (h *Handler) handleSyncStatus(req *restful.Request, resp *restful.Response) {
+	status, err := app.GetSyncStatus()
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, status))
+}
+
+func (h *Handler) handleSyncRuns(req *restful.Request, resp *restful.Response) {
+	page := req.QueryParameter("page")
+	size := req.QueryParameter("size")
+	status := req.QueryParameter("status")
+
+	from, sizeN := utils.VerifyFromAndSize(page, size)
+
+	runs, count, err := mongo.GetSyncRuns(int64(from), int64(sizeN), status)
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResultWithCount(runs, count)))
+}
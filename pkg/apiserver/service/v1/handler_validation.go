@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"app-store-server/internal/mongo"
+	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
+	"app-store-server/pkg/utils"
+	"errors"
+	"fmt"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+func (h *Handler) handleValidationReport(req *restful.Request, resp *restful.Response) {
+	appName := req.PathParameter(ParamAppName)
+	if appName == "" {
+		api.HandleBadRequest(resp, req, errors.New("param invalid"))
+		return
+	}
+
+	report, err := mongo.GetValidationReport(appName)
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+	if report == nil {
+		api.HandleNotFound(resp, req, fmt.Errorf("no validation report for %s", appName))
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, report))
+}
+
+func (h *Handler) handleValidationReports(req *restful.Request, resp *restful.Response) {
+	page := req.QueryParameter("page")
+	size := req.QueryParameter("size")
+	invalidOnly := req.QueryParameter("invalid") == "true"
+
+	from, sizeN := utils.VerifyFromAndSize(page, size)
+
+	reports, count, err := mongo.GetValidationReports(int64(from), int64(sizeN), invalidOnly)
+	if err != nil {
+		api.HandleError(resp, req, err)
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, models.NewListResultWithCount(reports, count)))
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"app-store-server/internal/app"
+	"app-store-server/internal/gitapp"
+	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/golang/glog"
+)
+
+const maxWebhookBodySize = 10 << 20
+
+func (h *Handler) handleGitWebhook(req *restful.Request, resp *restful.Response) {
+	body, err := io.ReadAll(io.LimitReader(req.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	err = gitapp.VerifyWebhookSignature(req.Request.Header, body)
+	if err != nil {
+		if errors.Is(err, gitapp.ErrWebhookSecretNotSet) {
+			api.HandleForbidden(resp, req, err)
+			return
+		}
+		if errors.Is(err, gitapp.ErrWebhookReplayed) {
+			api.HandleConflict(resp, req, err)
+			return
+		}
+		api.HandleUnauthorized(resp, req, err)
+		return
+	}
+
+	push, err := gitapp.ParseWebhookPush(body)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	source := push.TrackedSource()
+	res := &models.WebhookRes{
+		Ref:       push.Ref,
+		Triggered: source != nil,
+	}
+	if !res.Triggered {
+		glog.Infof("ignore push to %s, not a tracked branch", push.Ref)
+		resp.WriteEntity(models.NewResponse(api.OK, api.Success, res))
+		return
+	}
+
+	glog.Infof("push to %s of source %s (%s..%s), trigger git pull and update", push.Ref, source.Name, push.Before, push.After)
+	err = app.RequestGitPullAndUpdate(models.SyncTriggerWebhook)
+	if err != nil {
+		api.HandleError(resp, req, fmt.Errorf("request sync: %w", err))
+		return
+	}
+
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, res))
+}
@@ -15,6 +15,9 @@
 package v1
 
 import (
+	"app-store-server/internal/gitapp"
+	"app-store-server/pkg/api"
+	"app-store-server/pkg/models"
 	"fmt"
 	"net/http"
 
@@ -23,10 +26,14 @@ import (
 )
 
 const (
-	APIRootPath   = "/app-store-server"
-	Version       = "v1"
-	ParamAppName  = "name"
-	ParamAppNames = "names"
+	APIRootPath     = "/app-store-server"
+	Version         = "v1"
+	ParamAppName    = "name"
+	ParamAppNames   = "names"
+	ParamVariant    = "variant"
+	ParamLang       = "lang"
+	ParamI18n       = "i18n"
+	ParamMiddleware = "middleware"
 )
 
 var (
@@ -53,7 +60,13 @@ func AddToContainer(c *restful.Container) error {
 		Param(ws.QueryParameter("size", "size")).
 		Param(ws.QueryParameter("category", "category")).
 		Param(ws.QueryParameter("type", "type")).
+		Param(ws.QueryParameter("labels", "only the apps with all of these labels, comma separated")).
+		Param(ws.QueryParameter("excludedLabels", "leave out the apps with any of these labels, comma separated")).
+		Param(ws.QueryParameter(ParamMiddleware, "only the apps requesting all of these middleware kinds, comma separated, e.g. postgres,redis")).
 		Param(ws.QueryParameter("version", "version")).
+		Param(ws.QueryParameter(ParamVariant, "the render variant of templated manifests, all variants by default")).
+		Param(ws.QueryParameter(ParamLang, "the languages to resolve the app in, comma separated, Accept-Language by default")).
+		Param(ws.QueryParameter(ParamI18n, "false to leave out the i18n map")).
 		Returns(http.StatusOK, "success to get application list", nil))
 
 	glog.Infof("registered sub module: %s", ws.RootPath()+"/applications")
@@ -84,22 +97,58 @@ func AddToContainer(c *restful.Container) error {
 		Param(ws.QueryParameter("size", "size")).
 		Param(ws.QueryParameter("type", "type")).
 		Param(ws.QueryParameter("excludedLabels", "excludedLabels")).
+		Param(ws.QueryParameter(ParamMiddleware, "only the apps requesting all of these middleware kinds, comma separated, e.g. postgres,redis")).
 		Param(ws.QueryParameter("version", "version")).
+		Param(ws.QueryParameter(ParamVariant, "the render variant of templated manifests, all variants by default")).
+		Param(ws.QueryParameter(ParamLang, "the languages to resolve the app in, comma separated, Accept-Language by default")).
+		Param(ws.QueryParameter(ParamI18n, "false to leave out the i18n map")).
 		Returns(http.StatusOK, "success to get the top application list", nil))
 
+	ws.Route(ws.GET("/applications/middlewares").
+		To(handler.handleMiddlewares).
+		Doc("count the applications requesting each kind of middleware").
+		Returns(http.StatusOK, "success to count the applications per middleware", nil))
+
 	ws.Route(ws.GET("/applications/info/{"+ParamAppName+"}").
 		To(handler.handleAppInfo).
 		Doc("get the application info").
 		Param(ws.PathParameter(ParamAppName, "the name of the application")).
 		Param(ws.QueryParameter("version", "version")).
+		Param(ws.QueryParameter(ParamVariant, "the render variant of templated manifests, all variants by default")).
+		Param(ws.QueryParameter(ParamLang, "the languages to resolve the app in, comma separated, Accept-Language by default")).
+		Param(ws.QueryParameter(ParamI18n, "false to leave out the i18n map")).
 		Returns(http.StatusOK, "Success to get the application info", nil))
 
+	ws.Route(ws.GET("/applications/{"+ParamAppName+"}/dependencies").
+		To(handler.handleDependencies).
+		Doc("resolve the applications the application depends on, with the versions to install on the system version").
+		Param(ws.PathParameter(ParamAppName, "the name of the application")).
+		Param(ws.QueryParameter("version", "the system version")).
+		Returns(http.StatusOK, "Success to resolve the application dependencies", nil))
+
+	ws.Route(ws.POST("/applications/{"+ParamAppName+"}/installable").
+		To(handler.handleInstallCheck).
+		Doc("check whether the application can be installed or upgraded to next to the installed applications").
+		Param(ws.PathParameter(ParamAppName, "the name of the application")).
+		Param(ws.QueryParameter("version", "the system version")).
+		Reads(models.InstallCheckReq{}).
+		Returns(http.StatusOK, "Success to check the application", nil))
+
 	ws.Route(ws.GET("/applications/{"+ParamAppName+"}/README.md").
 		To(handler.handleReadme).
-		Doc("get the application readme info").
+		Doc("get the application readme info, relative links point at the application assets").
 		Param(ws.PathParameter(ParamAppName, "the name of the application")).
+		Param(ws.QueryParameter(ParamLang, "the languages to pick the readme in, comma separated, Accept-Language by default")).
+		Param(ws.QueryParameter(ParamFormat, "html to render the readme to sanitized HTML, markdown by default")).
 		Returns(http.StatusOK, "Success to get the application readme info", nil))
 
+	ws.Route(ws.GET("/applications/{"+ParamAppName+"}/assets/{"+ParamAssetPath+":*}").
+		To(handler.handleAsset).
+		Doc("get an image or media file of the application directory, e.g. an image of the readme").
+		Param(ws.PathParameter(ParamAppName, "the name of the application")).
+		Param(ws.PathParameter(ParamAssetPath, "the path of the file in the application directory, one of png, jpg, jpeg, gif, webp, avif, bmp, ico, svg, mp4, webm, mp3, ogg or wav")).
+		Returns(http.StatusOK, "Success to get the application asset", nil))
+
 	ws.Route(ws.POST("/applications/update").
 		To(handler.handleUpdate).
 		Doc("update applications").
@@ -107,6 +156,89 @@ func AddToContainer(c *restful.Container) error {
 
 	glog.Infof("registered sub module: %s", ws.RootPath()+"/application/update")
 
+	ws.Route(ws.POST("/applications/webhook").
+		To(handler.handleGitWebhook).
+		Doc("trigger applications update from a signed git push webhook (GitHub/Gitea/GitLab)").
+		Returns(http.StatusOK, "success to accept the git push webhook", models.WebhookRes{}).
+		Returns(http.StatusConflict, "the delivery was already received", nil))
+
+	glog.Infof("registered sub module: %s", ws.RootPath()+"/applications/webhook")
+
+	ws.Route(ws.GET("/admin/catalog/pins").
+		To(handler.handlePinStatus).
+		Filter(api.AdminAuth).
+		Doc("get the pin state of the catalog sources").
+		Returns(http.StatusOK, "success to get the pin state of the catalog sources", []gitapp.PinStatus{}))
+
+	ws.Route(ws.POST("/admin/catalog/pin").
+		To(handler.handlePin).
+		Filter(api.AdminAuth).
+		Doc("pin a catalog source to a tag or commit").
+		Reads(models.PinReq{}).
+		Returns(http.StatusOK, "success to pin the catalog source", models.PinRes{}))
+
+	ws.Route(ws.POST("/admin/catalog/unpin").
+		To(handler.handleUnpin).
+		Filter(api.AdminAuth).
+		Doc("make a catalog source track its branch again").
+		Reads(models.PinReq{}).
+		Returns(http.StatusOK, "success to unpin the catalog source", models.PinRes{}))
+
+	ws.Route(ws.POST("/admin/catalog/rollback").
+		To(handler.handleRollback).
+		Filter(api.AdminAuth).
+		Doc("roll a catalog source back to a previous commit, HEAD~1 by default").
+		Reads(models.PinReq{}).
+		Returns(http.StatusOK, "success to roll back the catalog source", models.PinRes{}))
+
+	ws.Route(ws.POST("/admin/catalog/bundle").
+		To(handler.handleUploadBundle).
+		Filter(api.AdminAuth).
+		Consumes("application/gzip", "application/x-gzip", "application/octet-stream").
+		Doc("upload a .tar.gz bundle of app folders for a local bundle source").
+		Param(ws.QueryParameter("source", "the name of the source, default by default")).
+		Returns(http.StatusOK, "success to upload the bundle", models.BundleRes{}).
+		Returns(http.StatusConflict, "the replica is not the leader, the error names the leader", nil))
+
+	glog.Infof("registered sub module: %s", ws.RootPath()+"/admin/catalog")
+
+	ws.Route(ws.GET("/admin/categories/policies").
+		To(handler.handleCategoryPolicies).
+		Filter(api.AdminAuth).
+		Doc("list the category policies").
+		Returns(http.StatusOK, "success to list the category policies", []models.CategoryPolicy{}))
+
+	ws.Route(ws.PUT("/admin/categories/policies").
+		To(handler.handleSetCategoryPolicy).
+		Filter(api.AdminAuth).
+		Doc("hide or label the apps of a category, the category matches exactly ignoring case").
+		Reads(models.CategoryPolicy{}).
+		Returns(http.StatusOK, "success to set the category policy", models.CategoryPolicy{}))
+
+	ws.Route(ws.DELETE("/admin/categories/policies/{"+ParamCategory+"}").
+		To(handler.handleDeleteCategoryPolicy).
+		Filter(api.AdminAuth).
+		Doc("remove the policy of a category").
+		Param(ws.PathParameter(ParamCategory, "the category")).
+		Returns(http.StatusOK, "success to remove the category policy", nil))
+
+	ws.Route(ws.GET("/admin/sync/status").
+		To(handler.handleSyncStatus).
+		Filter(api.AdminAuth).
+		Doc("get the sync state, the current and last runs and the rejected commits").
+		Returns(http.StatusOK, "success to get the sync status", models.SyncStatus{}))
+
+	ws.Route(ws.GET("/admin/sync/runs").
+		To(handler.handleSyncRuns).
+		Filter(api.AdminAuth).
+		Doc("get the sync run history, newest first").
+		Param(ws.QueryParameter("page", "page")).
+		Param(ws.QueryParameter("size", "size")).
+		Param(ws.QueryParameter("status", "running, success, failed or skipped")).
+		Returns(http.StatusOK, "success to get the sync run history", []models.SyncRun{}))
+
+	glog.Infof("registered sub module: %s", ws.RootPath()+"/admin/sync")
+
 	ws.Route(ws.GET("/applications/search/{"+ParamAppName+"}").
 		To(handler.handleSearch).
 		Param(ws.PathParameter(ParamAppName, "the name of the application")).
@@ -114,13 +246,30 @@ func AddToContainer(c *restful.Container) error {
 		Param(ws.QueryParameter("size", "size")).
 		Param(ws.QueryParameter("version", "version")).
 		Doc("search application list by name").
+		Param(ws.QueryParameter(ParamVariant, "the render variant of templated manifests, all variants by default")).
+		Param(ws.QueryParameter(ParamLang, "the languages to resolve the app in, comma separated, Accept-Language by default")).
+		Param(ws.QueryParameter(ParamI18n, "false to leave out the i18n map")).
 		Returns(http.StatusOK, "success to search application list by name", nil))
 
 	ws.Route(ws.GET("/applications/version-history/{"+ParamAppName+"}").
 		To(handler.handleVersionHistory).
 		Param(ws.PathParameter(ParamAppName, "the name of the application")).
 		Doc("get application version history by name").
-		Returns(http.StatusOK, "success to get application version history by name", nil))
+		Returns(http.StatusOK, "success to get application version history by name", []models.AppVersionHistory{}))
+
+	ws.Route(ws.GET("/applications/validation").
+		To(handler.handleValidationReports).
+		Param(ws.QueryParameter("page", "page")).
+		Param(ws.QueryParameter("size", "size")).
+		Param(ws.QueryParameter("invalid", "true to list only the apps with errors")).
+		Doc("get the manifest validation reports of the applications").
+		Returns(http.StatusOK, "success to get the manifest validation reports", []models.ValidationReport{}))
+
+	ws.Route(ws.GET("/applications/validation/{"+ParamAppName+"}").
+		To(handler.handleValidationReport).
+		Param(ws.PathParameter(ParamAppName, "the directory name of the application, with the prefix of its source")).
+		Doc("get the manifest validation report of an application").
+		Returns(http.StatusOK, "success to get the manifest validation report", models.ValidationReport{}))
 
 	ws.Route(ws.GET("/applications/exist/{"+ParamAppName+"}").
 		To(handler.handleExist).
@@ -138,6 +287,7 @@ func AddToContainer(c *restful.Container) error {
 		To(handler.handleInfos).
 		Param(ws.BodyParameter(ParamAppNames, "the name list of the application")).
 		Doc("check app updates").
+		Param(ws.QueryParameter(ParamVariant, "the render variant of templated manifests, all variants by default")).
 		Reads([]string{}).
 		Returns(http.StatusOK, "success to check app updates", nil))
 
@@ -146,6 +296,7 @@ func AddToContainer(c *restful.Container) error {
 		Param(ws.PathParameter("version", "version")).
 		Param(ws.BodyParameter(ParamAppNames, "the name list of the application")).
 		Doc("check app updates").
+		Param(ws.QueryParameter(ParamVariant, "the render variant of templated manifests, all variants by default")).
 		Reads([]string{}).
 		Returns(http.StatusOK, "success to check app updates", nil))
 
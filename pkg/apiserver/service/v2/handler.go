@@ -53,7 +53,7 @@ func getTopsData() ([]AppStoreTopItem, error) {
 	excludedLabels := []string{}
 	sizeN := 10000 // Default top size
 
-	infos, err := mongo.GetTopApplicationInfos("", "", excludedLabels, sizeN)
+	infos, err := mongo.GetTopApplicationInfos("", "", excludedLabels, nil, sizeN)
 	if err != nil {
 		glog.Errorf("Failed to get top application infos: %v", err)
 		return nil, err
@@ -127,7 +127,7 @@ func getTopsDataWithVersion(version string) ([]AppStoreTopItem, error) {
 	excludedLabels := []string{}
 	sizeN := 10000 // Default top size
 
-	infos, err := mongo.GetTopApplicationInfos("", "", excludedLabels, sizeN)
+	infos, err := mongo.GetTopApplicationInfos("", "", excludedLabels, nil, sizeN)
 	if err != nil {
 		glog.Errorf("Failed to get top application infos: %v", err)
 		return nil, err
@@ -246,7 +246,7 @@ func (h *Handler) handleAppStoreInfo(req *restful.Request, resp *restful.Respons
 		AppStore: appStoreInfo,
 	}
 
-	resp.WriteEntity(models.NewResponse(api.OK, api.Success, response))
+	resp.WriteEntity(models.NewResponse(api.OK, api.Success, api.Project(req, response)))
 }
 
 // handleChartDownload handles the request to download application chart
@@ -0,0 +1,29 @@
+package models
+
+import "strings"
+
+const (
+	CategoryActionHide  = "hide"
+	CategoryActionLabel = "label"
+)
+
+// CategoryPolicy hides the apps of a category or adds a label to them. The
+// category matches exactly, ignoring case.
+type CategoryPolicy struct {
+	Category string `json:"category" bson:"category"`
+	Action   string `json:"action" bson:"action"`
+	// Label is what the label action adds to the apps of the category
+	Label      string `json:"label,omitempty" bson:"label,omitempty"`
+	UpdateTime int64  `json:"updateTime" bson:"updateTime"`
+}
+
+// Matches reports whether one of categories is the category of the policy.
+func (p CategoryPolicy) Matches(categories []string) bool {
+	for _, c := range categories {
+		if strings.EqualFold(c, p.Category) {
+			return true
+		}
+	}
+
+	return false
+}
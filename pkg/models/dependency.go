@@ -0,0 +1,98 @@
+package models
+
+const (
+	DependencyProblemMissing       = "missing"
+	DependencyProblemUnsatisfiable = "unsatisfiable"
+	DependencyProblemInvalid       = "invalid-constraint"
+	DependencyProblemCycle         = "cycle"
+)
+
+// DependencyNode is an app of a resolved dependency tree.
+type DependencyNode struct {
+	Name string `json:"name"`
+	// Constraint and Mandatory come from the app depending on this one, empty for the root
+	Constraint string `json:"constraint,omitempty"`
+	Mandatory  bool   `json:"mandatory"`
+	// Version and ChartName are empty when no version satisfies the constraints
+	Version      string            `json:"version,omitempty"`
+	ChartName    string            `json:"chartName,omitempty"`
+	Dependencies []*DependencyNode `json:"dependencies,omitempty"`
+	// Cycle marks an app already on the path from the root, its dependencies are not repeated
+	Cycle bool `json:"cycle,omitempty"`
+}
+
+// DependencyProblem is a dependency of an app that cannot be resolved.
+type DependencyProblem struct {
+	App        string `json:"app" bson:"app"`
+	Dependency string `json:"dependency" bson:"dependency"`
+	Kind       string `json:"kind" bson:"kind"`
+	Constraint string `json:"constraint,omitempty" bson:"constraint"`
+	Mandatory  bool   `json:"mandatory" bson:"mandatory"`
+	Message    string `json:"message" bson:"message"`
+}
+
+// ResolvedApp is an app to install with the version picked for it.
+type ResolvedApp struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	ChartName string `json:"chartName"`
+}
+
+// DependencyResolution is the dependency tree of an app for a system version.
+type DependencyResolution struct {
+	Name          string          `json:"name"`
+	SystemVersion string          `json:"systemVersion,omitempty"`
+	Tree          *DependencyNode `json:"tree"`
+	// Apps lists the resolved apps dependencies first, the root last
+	Apps     []ResolvedApp       `json:"apps"`
+	Problems []DependencyProblem `json:"problems"`
+	// Resolvable is false when a mandatory dependency has a problem
+	Resolvable bool `json:"resolvable"`
+}
+
+const (
+	InstallActionInstall = "install"
+	InstallActionUpgrade = "upgrade"
+
+	// InstallReasonConflict is an installed app the candidate conflicts with
+	InstallReasonConflict = "conflict"
+	// InstallReasonConflictedBy is an installed app conflicting with the candidate
+	InstallReasonConflictedBy = "conflicted-by"
+	// InstallReasonClusterScopedMissing is a cluster-scoped dependency not installed,
+	// it cannot be installed along with the candidate
+	InstallReasonClusterScopedMissing = "cluster-scoped-missing"
+	// InstallReasonAppRef is a cluster-scoped dependency not referable by the candidate
+	InstallReasonAppRef = "app-ref"
+	// InstallReasonDependencyVersion is an installed dependency whose version does not satisfy the candidate
+	InstallReasonDependencyVersion = "dependency-version"
+	// InstallReasonBreaksDependent is an installed app whose dependency on the candidate the version breaks
+	InstallReasonBreaksDependent = "breaks-dependent"
+	// InstallReasonNotNewer is an installed version not older than the candidate
+	InstallReasonNotNewer = "not-newer"
+)
+
+// InstallCheckReq holds the apps installed on the client, by name, and the
+// version of the candidate, the newest one running on the system by default.
+type InstallCheckReq struct {
+	Installed map[string]string `json:"installed"`
+	Version   string            `json:"version"`
+}
+
+type InstallReason struct {
+	Kind string `json:"kind"`
+	// App is the other app involved
+	App        string `json:"app"`
+	Constraint string `json:"constraint,omitempty"`
+	Message    string `json:"message"`
+}
+
+// InstallCheckRes tells whether the candidate can be installed or upgraded
+// to, Reasons lists why not.
+type InstallCheckRes struct {
+	Name             string          `json:"name"`
+	Version          string          `json:"version"`
+	InstalledVersion string          `json:"installedVersion,omitempty"`
+	Action           string          `json:"action"`
+	Installable      bool            `json:"installable"`
+	Reasons          []InstallReason `json:"reasons"`
+}
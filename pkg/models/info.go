@@ -80,6 +80,7 @@ func (ac *AppConfiguration) ToAppInfo() *ApplicationInfoEntry {
 		Permission:         ac.Permission,
 		Entrances:          ac.Entrances,
 		Middleware:         ac.Middleware,
+		MiddlewareSummary:  NewMiddlewareSummary(ac.Middleware),
 		Options:            ac.Options,
 		Locale:             ac.Spec.Locale,
 		Submitter:          ac.Spec.Submitter,
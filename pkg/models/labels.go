@@ -0,0 +1,24 @@
+package models
+
+// AppLabel is a label an app declares in its labels.yaml.
+type AppLabel struct {
+	Name   string `json:"name" bson:"name"`
+	Reason string `json:"reason,omitempty" bson:"reason,omitempty"`
+	// EffectiveAt and ExpireAt bound the time the label applies, unix seconds, 0 when open
+	EffectiveAt int64 `json:"effectiveAt,omitempty" bson:"effectiveAt,omitempty"`
+	ExpireAt    int64 `json:"expireAt,omitempty" bson:"expireAt,omitempty"`
+	// Versions is the semver constraint of the app versions the label applies to, all when empty
+	Versions string `json:"versions,omitempty" bson:"versions,omitempty"`
+}
+
+// ActiveAt reports whether the label applies at the unix time now.
+func (l AppLabel) ActiveAt(now int64) bool {
+	if l.EffectiveAt > 0 && now < l.EffectiveAt {
+		return false
+	}
+	if l.ExpireAt > 0 && now >= l.ExpireAt {
+		return false
+	}
+
+	return true
+}
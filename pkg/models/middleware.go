@@ -0,0 +1,103 @@
+package models
+
+import (
+	"app-store-server/pkg/models/tapr"
+	"strings"
+)
+
+const (
+	MiddlewarePostgres   = "postgres"
+	MiddlewareRedis      = "redis"
+	MiddlewareMongoDB    = "mongodb"
+	MiddlewareZincSearch = "zincsearch"
+)
+
+// MiddlewareUsage is what an app requests of a kind of middleware.
+type MiddlewareUsage struct {
+	Kind string `json:"kind" bson:"kind"`
+	// Databases counts the databases, or the indexes of zincsearch
+	Databases            int `json:"databases" bson:"databases"`
+	DistributedDatabases int `json:"distributedDatabases" bson:"distributedDatabases"`
+}
+
+// MiddlewareSummary normalizes the middleware an app version requests.
+type MiddlewareSummary struct {
+	Kinds       []string          `json:"kinds" bson:"kinds"`
+	Databases   int               `json:"databases" bson:"databases"`
+	Distributed bool              `json:"distributed" bson:"distributed"`
+	Middlewares []MiddlewareUsage `json:"middlewares" bson:"middlewares"`
+}
+
+// MiddlewareCount is the number of apps requesting a kind of middleware.
+type MiddlewareCount struct {
+	Kind            string `json:"kind" bson:"_id"`
+	Apps            int64  `json:"apps" bson:"apps"`
+	Databases       int64  `json:"databases" bson:"databases"`
+	DistributedApps int64  `json:"distributedApps" bson:"distributedApps"`
+}
+
+func databaseUsage(kind string, databases []tapr.Database) MiddlewareUsage {
+	usage := MiddlewareUsage{Kind: NormalizeMiddlewareKind(kind), Databases: len(databases)}
+	for _, db := range databases {
+		if db.Distributed {
+			usage.DistributedDatabases++
+		}
+	}
+
+	return usage
+}
+
+// NewMiddlewareSummary summarizes the middleware request of an app, nil when
+// it requests none.
+func NewMiddlewareSummary(m *tapr.Middleware) *MiddlewareSummary {
+	if m == nil {
+		return nil
+	}
+
+	var usages []MiddlewareUsage
+	if m.Postgres != nil {
+		usages = append(usages, databaseUsage(MiddlewarePostgres, m.Postgres.Databases))
+	}
+	if m.Redis != nil {
+		usages = append(usages, databaseUsage(MiddlewareRedis, m.Redis.Databases))
+	}
+	if m.MongoDB != nil {
+		usages = append(usages, databaseUsage(MiddlewareMongoDB, m.MongoDB.Databases))
+	}
+	if m.ZincSearch != nil {
+		usages = append(usages, MiddlewareUsage{Kind: NormalizeMiddlewareKind(MiddlewareZincSearch), Databases: len(m.ZincSearch.Indexes)})
+	}
+	if len(usages) == 0 {
+		return nil
+	}
+
+	summary := &MiddlewareSummary{Middlewares: usages}
+	for _, u := range usages {
+		summary.Kinds = append(summary.Kinds, u.Kind)
+		summary.Databases += u.Databases
+		if u.DistributedDatabases > 0 {
+			summary.Distributed = true
+		}
+	}
+
+	return summary
+}
+
+// middlewareAliases maps the other names of the middleware kinds to the kinds
+var middlewareAliases = map[string]string{
+	"postgresql": MiddlewarePostgres,
+	"pg":         MiddlewarePostgres,
+	"mongo":      MiddlewareMongoDB,
+	"zinc":       MiddlewareZincSearch,
+}
+
+// NormalizeMiddlewareKind maps the spellings of a middleware kind, e.g.
+// ZincSearch, MongoDB or postgresql, to the kind of the summaries.
+func NormalizeMiddlewareKind(kind string) string {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if alias, ok := middlewareAliases[kind]; ok {
+		return alias
+	}
+
+	return kind
+}
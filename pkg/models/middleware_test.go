@@ -0,0 +1,58 @@
+package models
+
+import (
+	"app-store-server/pkg/models/tapr"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeMiddlewareKind(t *testing.T) {
+	tests := map[string]string{
+		"postgres":   MiddlewarePostgres,
+		"PostgreSQL": MiddlewarePostgres,
+		" pg ":       MiddlewarePostgres,
+		"MongoDB":    MiddlewareMongoDB,
+		"mongo":      MiddlewareMongoDB,
+		"ZincSearch": MiddlewareZincSearch,
+		"zinc":       MiddlewareZincSearch,
+		"redis":      MiddlewareRedis,
+		"mysql":      "mysql",
+		"":           "",
+	}
+
+	for kind, want := range tests {
+		if got := NormalizeMiddlewareKind(kind); got != want {
+			t.Errorf("NormalizeMiddlewareKind(%q) = %q, want %q", kind, got, want)
+		}
+	}
+}
+
+func TestNewMiddlewareSummary(t *testing.T) {
+	if got := NewMiddlewareSummary(nil); got != nil {
+		t.Errorf("summary of no middleware = %+v, want nil", got)
+	}
+	if got := NewMiddlewareSummary(&tapr.Middleware{}); got != nil {
+		t.Errorf("summary of an empty middleware = %+v, want nil", got)
+	}
+
+	m := &tapr.Middleware{
+		Postgres: &tapr.PostgresConfig{Databases: []tapr.Database{
+			{Name: "main", Distributed: true},
+			{Name: "cache"},
+		}},
+		ZincSearch: &tapr.ZincSearchConfig{Indexes: []tapr.Index{{Name: "docs"}}},
+	}
+	want := &MiddlewareSummary{
+		Kinds:       []string{MiddlewarePostgres, MiddlewareZincSearch},
+		Databases:   3,
+		Distributed: true,
+		Middlewares: []MiddlewareUsage{
+			{Kind: MiddlewarePostgres, Databases: 2, DistributedDatabases: 1},
+			{Kind: MiddlewareZincSearch, Databases: 1},
+		},
+	}
+
+	if got := NewMiddlewareSummary(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+const (
+	SyncStatusRunning = "running"
+	SyncStatusSuccess = "success"
+	SyncStatusFailed  = "failed"
+	// SyncStatusSkipped marks a phase that did not run, e.g. nothing to process
+	SyncStatusSkipped = "skipped"
+
+	SyncTriggerStartup  = "startup"
+	SyncTriggerSchedule = "schedule"
+	SyncTriggerManual   = "manual"
+	SyncTriggerWebhook  = "webhook"
+	SyncTriggerAdmin    = "admin"
+	// SyncTriggerTakeover is the full run of a replica taking over the leadership
+	SyncTriggerTakeover = "takeover"
+)
+
+// SyncRun is one catalog sync, from the pull to the image processing.
+type SyncRun struct {
+	Id      string `json:"id" bson:"id"`
+	Trigger string `json:"trigger" bson:"trigger"`
+	// Full is false when only the changed app directories were re-ingested
+	Full   bool   `json:"full" bson:"full"`
+	Status string `json:"status" bson:"status"`
+	Error  string `json:"error,omitempty" bson:"error"`
+
+	StartTime int64 `json:"startTime" bson:"startTime"`
+	EndTime   int64 `json:"endTime" bson:"endTime"`
+	// Duration of the pull and ingestion in milliseconds, the ES and image phases are timed separately
+	Duration int64 `json:"duration" bson:"duration"`
+
+	OldCommit     string            `json:"oldCommit" bson:"oldCommit"`
+	NewCommit     string            `json:"newCommit" bson:"newCommit"`
+	SourceCommits map[string]string `json:"sourceCommits,omitempty" bson:"sourceCommits"`
+
+	Succeeded []string         `json:"succeeded" bson:"succeeded"`
+	Failed    []SyncAppFailure `json:"failed" bson:"failed"`
+	Removed   []string         `json:"removed,omitempty" bson:"removed"`
+	// DependencyProblems lists the app dependencies of the catalog that cannot be resolved
+	DependencyProblems []DependencyProblem `json:"dependencyProblems,omitempty" bson:"dependencyProblems"`
+
+	ESPhase    SyncPhase `json:"esPhase" bson:"esPhase"`
+	ImagePhase SyncPhase `json:"imagePhase" bson:"imagePhase"`
+}
+
+type SyncAppFailure struct {
+	Name  string `json:"name" bson:"name"`
+	Error string `json:"error" bson:"error"`
+}
+
+type SyncPhase struct {
+	Status    string           `json:"status" bson:"status"`
+	StartTime int64            `json:"startTime,omitempty" bson:"startTime"`
+	EndTime   int64            `json:"endTime,omitempty" bson:"endTime"`
+	Error     string           `json:"error,omitempty" bson:"error"`
+	Failed    []SyncAppFailure `json:"failed,omitempty" bson:"failed"`
+}
+
+// CommitRejection is a pulled commit refused by signature verification.
+type CommitRejection struct {
+	Source string `json:"source" bson:"source"`
+	Commit string `json:"commit" bson:"commit"`
+	// Accepted is the commit the source was reset to and keeps serving, empty
+	// when no commit of the source was accepted yet and it serves no apps
+	Accepted string `json:"accepted" bson:"accepted"`
+	Reason   string `json:"reason" bson:"reason"`
+	Time     int64  `json:"time" bson:"time"`
+}
+
+// SyncStatus is the live state of the sync machinery.
+type SyncStatus struct {
+	// Replica is the name of the answering replica, Leader the one syncing the catalog
+	Replica  string `json:"replica"`
+	Leader   string `json:"leader"`
+	IsLeader bool   `json:"isLeader"`
+
+	Running         bool `json:"running"`
+	Pending         bool `json:"pending"`
+	Ingesting       bool `json:"ingesting"`
+	ProcessingImage bool `json:"processingImage"`
+	// LastCheckTime is the last time the sources were checked for changes
+	LastCheckTime int64 `json:"lastCheckTime"`
+
+	CurrentRun     *SyncRun          `json:"currentRun,omitempty"`
+	LastRun        *SyncRun          `json:"lastRun,omitempty"`
+	LastSuccessRun *SyncRun          `json:"lastSuccessRun,omitempty"`
+	Rejections     []CommitRejection `json:"rejections"`
+}
@@ -28,25 +28,25 @@ type Database struct {
 
 type PostgresConfig struct {
 	Username  string     `yaml:"username" json:"username"`
-	Password  string     `yaml:"password,omitempty" json:"password"`
+	Password  string     `yaml:"password,omitempty" json:"password" sensitive:"true"`
 	Databases []Database `yaml:"databases" json:"databases"`
 }
 
 type RedisConfig struct {
 	Username  string     `yaml:"username" json:"username"`
-	Password  string     `yaml:"password,omitempty" json:"password"`
+	Password  string     `yaml:"password,omitempty" json:"password" sensitive:"true"`
 	Databases []Database `yaml:"databases" json:"databases"`
 }
 
 type MongodbConfig struct {
 	Username  string     `yaml:"username" json:"username"`
-	Password  string     `yaml:"password,omitempty" json:"password"`
+	Password  string     `yaml:"password,omitempty" json:"password" sensitive:"true"`
 	Databases []Database `yaml:"databases" json:"databases"`
 }
 
 type ZincSearchConfig struct {
 	Username string  `yaml:"username" json:"username"`
-	Password string  `yaml:"password" json:"password"`
+	Password string  `yaml:"password" json:"password" sensitive:"true"`
 	Indexes  []Index `yaml:"indexes" json:"indexes"`
 }
 
@@ -88,11 +88,17 @@ type ApplicationInfoEntry struct {
 	OnlyAdmin bool   `yaml:"onlyAdmin" json:"onlyAdmin" bson:"onlyAdmin"`
 
 	LastCommitHash string `yaml:"-" json:"lastCommitHash" bson:"lastCommitHash"`
+	Source         string `yaml:"-" json:"source,omitempty" bson:"source"`
 	CreateTime     int64  `yaml:"-" json:"createTime" bson:"createTime"`
 	UpdateTime     int64  `yaml:"-" json:"updateTime" bson:"updateTime"`
 	//Status         string   `yaml:"status" json:"status" bson:"status"`
 	AppLabels []string    `yaml:"appLabels" json:"appLabels,omitempty" bson:"appLabels"`
 	Count     interface{} `yaml:"count" json:"count" bson:"count"`
+	// Labels are the labels of labels.yaml for this version, AppLabels holds the names of the active ones
+	Labels []AppLabel `yaml:"-" json:"labels,omitempty" bson:"labels,omitempty"`
+
+	// MiddlewareSummary normalizes Middleware for indexing and filtering
+	MiddlewareSummary *MiddlewareSummary `yaml:"-" json:"middlewareSummary,omitempty" bson:"middlewareSummary,omitempty"`
 
 	Variants map[string]ApplicationInfoEntry `yaml:"variants" json:"variants,omitempty" bson:"variants"`
 }
@@ -103,6 +109,9 @@ type ApplicationInfoFullData struct {
 	Name      string                          `yaml:"name" json:"name" bson:"name"`
 	History   map[string]ApplicationInfoEntry `yaml:"history" json:"history" bson:"history"`
 	AppLabels []string                        `yaml:"appLabels" json:"appLabels,omitempty" bson:"appLabels"`
+	// RemovedAt is set when the app directory disappeared from the catalog,
+	// the app is deleted once the removal grace period is over
+	RemovedAt int64 `yaml:"-" json:"removedAt,omitempty" bson:"removedAt,omitempty"`
 }
 
 type AppSpec struct {
@@ -199,7 +208,7 @@ type Dependency struct {
 	// dependency type: system, application.
 	Type      string `yaml:"type" json:"type" bson:"type"`
 	Mandatory bool   `yaml:"mandatory" json:"mandatory" bson:"mandatory"`
-	SelfRely  bool   `yaml:"selfRely json:"selfRely"`
+	SelfRely  bool   `yaml:"selfRely" json:"selfRely"`
 }
 
 type AppScope struct {
@@ -215,3 +224,33 @@ type WsConfig struct {
 type ExistRes struct {
 	Exist bool `json:"exist"`
 }
+
+type WebhookRes struct {
+	Ref       string `json:"ref"`
+	Triggered bool   `json:"triggered"`
+}
+
+type PinReq struct {
+	Source string `json:"source"`
+	Ref    string `json:"ref"`
+}
+
+type BundleRes struct {
+	Source string `json:"source"`
+	Digest string `json:"digest"`
+}
+
+type PinRes struct {
+	Source string `json:"source"`
+	Ref    string `json:"ref"`
+	Commit string `json:"commit"`
+}
+
+// AppVersionHistory is a version of an app and the commit that introduced it.
+type AppVersionHistory struct {
+	Version            string `json:"version"`
+	Commit             string `json:"commit"`
+	Author             string `json:"author"`
+	Date               int64  `json:"date"`
+	UpgradeDescription string `json:"upgradeDescription"`
+}
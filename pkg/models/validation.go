@@ -0,0 +1,26 @@
+package models
+
+const (
+	ValidationError   = "error"
+	ValidationWarning = "warning"
+)
+
+// ValidationIssue is a problem one rule found in an OlaresManifest.yaml.
+type ValidationIssue struct {
+	Rule     string `json:"rule" bson:"rule"`
+	Severity string `json:"severity" bson:"severity"`
+	// Field is the path of the offending field, e.g. options.dependencies[0].version
+	Field   string `json:"field" bson:"field"`
+	Message string `json:"message" bson:"message"`
+}
+
+// ValidationReport holds the issues found in the manifest of an app. Apps
+// with errors are not published.
+type ValidationReport struct {
+	Name     string            `json:"name" bson:"name"`
+	Source   string            `json:"source" bson:"source"`
+	Time     int64             `json:"time" bson:"time"`
+	Valid    bool              `json:"valid" bson:"valid"`
+	Errors   []ValidationIssue `json:"errors" bson:"errors"`
+	Warnings []ValidationIssue `json:"warnings" bson:"warnings"`
+}
@@ -0,0 +1,158 @@
+// Package redact strips the fields of the models tagged sensitive:"true", e.g.
+// the middleware passwords, from the payloads of untrusted clients.
+package redact
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	TagName = "sensitive"
+	TagTrue = "true"
+
+	// EnabledEnv turns the redaction of the payloads on. It is off by default:
+	// installers that do not send the installer token need the credentials.
+	EnabledEnv = "REDACT_SENSITIVE_FIELDS"
+)
+
+// Enabled reports whether the sensitive fields are redacted from the
+// payloads of untrusted clients and from the search index.
+func Enabled() bool {
+	return strings.EqualFold(os.Getenv(EnabledEnv), "true")
+}
+
+// sensitiveTypes caches whether the values of a type can hold a sensitive field
+var sensitiveTypes sync.Map
+
+// Redact returns a copy of v with the sensitive fields cleared, at any depth
+// of structs, pointers, slices, arrays, maps and interfaces. v is left as it is.
+func Redact[T any](v T) T {
+	src := reflect.ValueOf(&v).Elem()
+	if !canHoldSensitive(src.Type()) {
+		return v
+	}
+
+	// set through reflection, a nil interface does not convert back to T
+	var out T
+	reflect.ValueOf(&out).Elem().Set(redactValue(src))
+
+	return out
+}
+
+func canHoldSensitive(t reflect.Type) bool {
+	if cached, ok := sensitiveTypes.Load(t); ok {
+		return cached.(bool)
+	}
+
+	sensitive := hasSensitive(t, make(map[reflect.Type]bool))
+	sensitiveTypes.Store(t, sensitive)
+
+	return sensitive
+}
+
+// hasSensitive reports whether values of type t can hold a sensitive field,
+// so that copying them can be skipped.
+func hasSensitive(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return hasSensitive(t.Elem(), seen)
+	case reflect.Map:
+		return hasSensitive(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Tag.Get(TagName) == TagTrue || hasSensitive(f.Type, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// redactValue returns a redacted copy of v, sharing the parts without
+// sensitive fields.
+func redactValue(v reflect.Value) reflect.Value {
+	if !canHoldSensitive(v.Type()) {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(redactValue(v.Elem()))
+		return out
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(redactValue(v.Elem()))
+		return out
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return out
+
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), redactValue(iter.Value()))
+		}
+		return out
+
+	case reflect.Struct:
+		// copy the unexported fields as they are, then redact the exported ones
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Tag.Get(TagName) == TagTrue {
+				out.Field(i).Set(reflect.Zero(f.Type))
+				continue
+			}
+			out.Field(i).Set(redactValue(v.Field(i)))
+		}
+		return out
+	}
+
+	return v
+}
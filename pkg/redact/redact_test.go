@@ -0,0 +1,135 @@
+package redact
+
+import (
+	"app-store-server/pkg/models"
+	"app-store-server/pkg/models/tapr"
+	"testing"
+)
+
+func middleware() *tapr.Middleware {
+	return &tapr.Middleware{
+		Postgres:   &tapr.PostgresConfig{Username: "pg", Password: "pg-secret"},
+		Redis:      &tapr.RedisConfig{Username: "redis", Password: "redis-secret"},
+		MongoDB:    &tapr.MongodbConfig{Username: "mongo", Password: "mongo-secret"},
+		ZincSearch: &tapr.ZincSearchConfig{Username: "zinc", Password: "zinc-secret"},
+	}
+}
+
+func entry(version string) models.ApplicationInfoEntry {
+	return models.ApplicationInfoEntry{Name: "app", Version: version, Middleware: middleware()}
+}
+
+func fullData() *models.ApplicationInfoFullData {
+	latest := entry("1.0.0")
+	latest.Variants = map[string]models.ApplicationInfoEntry{"gpu": entry("1.0.0")}
+
+	return &models.ApplicationInfoFullData{
+		Id:      "id",
+		Name:    "app",
+		History: map[string]models.ApplicationInfoEntry{"latest": latest, "1.0.0": entry("1.0.0")},
+	}
+}
+
+// checkMiddleware fails when a password of m is left, or the other fields are lost.
+func checkMiddleware(t *testing.T, where string, m *tapr.Middleware) {
+	t.Helper()
+
+	if m == nil {
+		t.Fatalf("%s: middleware dropped", where)
+	}
+	if m.Postgres.Password != "" || m.Redis.Password != "" || m.MongoDB.Password != "" || m.ZincSearch.Password != "" {
+		t.Errorf("%s: password left in %+v %+v %+v %+v", where, m.Postgres, m.Redis, m.MongoDB, m.ZincSearch)
+	}
+	if m.Postgres.Username != "pg" || m.ZincSearch.Username != "zinc" {
+		t.Errorf("%s: username lost", where)
+	}
+}
+
+func checkFullData(t *testing.T, info *models.ApplicationInfoFullData) {
+	t.Helper()
+
+	if info.Id != "id" || info.Name != "app" {
+		t.Errorf("fields lost: %+v", info)
+	}
+	for key, e := range info.History {
+		checkMiddleware(t, "history "+key, e.Middleware)
+	}
+	gpu, ok := info.History["latest"].Variants["gpu"]
+	if !ok {
+		t.Fatalf("variant dropped")
+	}
+	checkMiddleware(t, "variant", gpu.Middleware)
+}
+
+func TestRedactFullData(t *testing.T) {
+	info := fullData()
+	checkFullData(t, Redact(info))
+
+	// the original keeps its passwords
+	if info.History["latest"].Middleware.Postgres.Password != "pg-secret" ||
+		info.History["latest"].Variants["gpu"].Middleware.Redis.Password != "redis-secret" {
+		t.Errorf("original redacted")
+	}
+}
+
+func TestRedactInterfaces(t *testing.T) {
+	list := Redact(models.NewListResult([]*models.ApplicationInfoFullData{fullData()}))
+	items, ok := list.Items.([]*models.ApplicationInfoFullData)
+	if !ok || len(items) != 1 {
+		t.Fatalf("items = %#v", list.Items)
+	}
+	checkFullData(t, items[0])
+
+	var v interface{} = map[string]interface{}{"app": entry("1.0.0")}
+	e, ok := Redact(v).(map[string]interface{})["app"].(models.ApplicationInfoEntry)
+	if !ok {
+		t.Fatalf("entry dropped")
+	}
+	checkMiddleware(t, "interface", e.Middleware)
+
+	resp := Redact(models.NewResponse(0, "success", []models.ApplicationInfoEntry{entry("1.0.0")}))
+	entries, ok := resp.Data.([]models.ApplicationInfoEntry)
+	if !ok || len(entries) != 1 {
+		t.Fatalf("data = %#v", resp.Data)
+	}
+	checkMiddleware(t, "response", entries[0].Middleware)
+}
+
+func TestRedactNil(t *testing.T) {
+	if Redact[*models.ApplicationInfoFullData](nil) != nil {
+		t.Errorf("nil pointer not kept")
+	}
+	if Redact[interface{}](nil) != nil {
+		t.Errorf("nil interface not kept")
+	}
+
+	info := Redact(&models.ApplicationInfoFullData{Name: "app"})
+	if info.History != nil {
+		t.Errorf("nil map not kept")
+	}
+}
+
+func TestRedactWithoutSensitiveFields(t *testing.T) {
+	type plain struct {
+		Name string
+		Tags []string
+	}
+
+	v := plain{Name: "app", Tags: []string{"a"}}
+	out := Redact(v)
+	// types without sensitive fields are returned as they are
+	if out.Name != "app" || &out.Tags[0] != &v.Tags[0] {
+		t.Errorf("plain value copied: %+v", out)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	tests := map[string]bool{"": false, "false": false, "true": true, "TRUE": true}
+
+	for value, want := range tests {
+		t.Setenv(EnabledEnv, value)
+		if got := Enabled(); got != want {
+			t.Errorf("Enabled with %q = %v, want %v", value, got, want)
+		}
+	}
+}